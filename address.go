@@ -0,0 +1,35 @@
+package nmcjson
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kefkius/nmcjson/internal/addrcodec"
+)
+
+// addressNet returns the address encoding of p.
+func (p *Params) addressNet() addrcodec.Net {
+	return addrcodec.Net{
+		Name:             p.Name,
+		PubKeyHashAddrID: p.PubKeyHashAddrID,
+		ScriptHashAddrID: p.ScriptHashAddrID,
+		Bech32HRP:        p.Bech32HRP,
+	}
+}
+
+// validateAddress checks that addr is a base58 P2PKH or P2SH address, or a
+// bech32 segwit version 0 address, of the network of p, or of any network
+// if p is nil.  The address package handles addresses beyond validation.
+func validateAddress(addr string, p *Params) error {
+	if p != nil {
+		_, _, err := addrcodec.Decode(addr, p.addressNet())
+		return err
+	}
+	for _, p := range networks {
+		_, _, err := addrcodec.Decode(addr, p.addressNet())
+		if err == nil || !errors.Is(err, addrcodec.ErrWrongNetwork) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %q is not a Namecoin address", addrcodec.ErrWrongNetwork, addr)
+}
@@ -0,0 +1,219 @@
+package address
+
+import (
+	"errors"
+
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/internal/addrcodec"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+var (
+	// ErrUnknownFormat indicates a string which is not an address of a
+	// supported kind.
+	ErrUnknownFormat = addrcodec.ErrUnknownFormat
+
+	// ErrWrongNetwork indicates an address of another network than the
+	// one it was decoded for.
+	ErrWrongNetwork = addrcodec.ErrWrongNetwork
+
+	// ErrNonStandardScript indicates a script which does not pay to an
+	// address.
+	ErrNonStandardScript = addrcodec.ErrNonStandardScript
+)
+
+// Address is an address of a Namecoin network.
+type Address interface {
+	// String returns the encoded address.
+	String() string
+
+	// Script returns the output script paying to the address.
+	Script() []byte
+
+	// Params returns the params of the network of the address.
+	Params() *nmcjson.Params
+}
+
+// Enforce that the address types satisfy the Address interface.
+var (
+	_ Address = (*PubKeyHash)(nil)
+	_ Address = (*ScriptHash)(nil)
+	_ Address = (*Witness)(nil)
+)
+
+// errNoParams is returned when no network params are given.
+var errNoParams = errors.New("address: params are required")
+
+// net returns the address encoding of params.
+func net(params *nmcjson.Params) addrcodec.Net {
+	return addrcodec.Net{
+		Name:             params.Name,
+		PubKeyHashAddrID: params.PubKeyHashAddrID,
+		ScriptHashAddrID: params.ScriptHashAddrID,
+		Bech32HRP:        params.Bech32HRP,
+	}
+}
+
+// PubKeyHash is a base58 pay-to-pubkey-hash address.
+type PubKeyHash struct {
+	hash   [addrcodec.HashLength]byte
+	params *nmcjson.Params
+}
+
+// NewPubKeyHash returns the P2PKH address of the 20 byte hash on the network
+// of params.
+func NewPubKeyHash(hash []byte, params *nmcjson.Params) (*PubKeyHash, error) {
+	if params == nil {
+		return nil, errNoParams
+	}
+	if err := addrcodec.CheckLength(addrcodec.PubKeyHash, hash); err != nil {
+		return nil, err
+	}
+	a := &PubKeyHash{params: params}
+	copy(a.hash[:], hash)
+	return a, nil
+}
+
+// Hash returns the pubkey hash of a.
+func (a *PubKeyHash) Hash() []byte {
+	return a.hash[:]
+}
+
+// String returns the base58 encoding of a.
+func (a *PubKeyHash) String() string {
+	return addrcodec.Encode(addrcodec.PubKeyHash, a.hash[:], net(a.params))
+}
+
+// Script returns OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG.
+func (a *PubKeyHash) Script() []byte {
+	return addrcodec.Script(addrcodec.PubKeyHash, a.hash[:])
+}
+
+// Params returns the params of the network of a.
+func (a *PubKeyHash) Params() *nmcjson.Params {
+	return a.params
+}
+
+// ScriptHash is a base58 pay-to-script-hash address.
+type ScriptHash struct {
+	hash   [addrcodec.HashLength]byte
+	params *nmcjson.Params
+}
+
+// NewScriptHash returns the P2SH address of the 20 byte hash on the network
+// of params.
+func NewScriptHash(hash []byte, params *nmcjson.Params) (*ScriptHash, error) {
+	if params == nil {
+		return nil, errNoParams
+	}
+	if err := addrcodec.CheckLength(addrcodec.ScriptHash, hash); err != nil {
+		return nil, err
+	}
+	a := &ScriptHash{params: params}
+	copy(a.hash[:], hash)
+	return a, nil
+}
+
+// Hash returns the script hash of a.
+func (a *ScriptHash) Hash() []byte {
+	return a.hash[:]
+}
+
+// String returns the base58 encoding of a.
+func (a *ScriptHash) String() string {
+	return addrcodec.Encode(addrcodec.ScriptHash, a.hash[:], net(a.params))
+}
+
+// Script returns OP_HASH160 <hash> OP_EQUAL.
+func (a *ScriptHash) Script() []byte {
+	return addrcodec.Script(addrcodec.ScriptHash, a.hash[:])
+}
+
+// Params returns the params of the network of a.
+func (a *ScriptHash) Params() *nmcjson.Params {
+	return a.params
+}
+
+// Witness is a bech32 segwit version 0 address, paying to a 20 byte pubkey
+// hash or a 32 byte script hash.
+type Witness struct {
+	program []byte
+	params  *nmcjson.Params
+}
+
+// NewWitness returns the segwit version 0 address of program on the network
+// of params.
+func NewWitness(program []byte, params *nmcjson.Params) (*Witness, error) {
+	if params == nil {
+		return nil, errNoParams
+	}
+	if err := addrcodec.CheckLength(addrcodec.Witness, program); err != nil {
+		return nil, err
+	}
+	return &Witness{
+		program: append([]byte(nil), program...),
+		params:  params,
+	}, nil
+}
+
+// Program returns the witness program of a.
+func (a *Witness) Program() []byte {
+	return a.program
+}
+
+// String returns the bech32 encoding of a.
+func (a *Witness) String() string {
+	return addrcodec.Encode(addrcodec.Witness, a.program, net(a.params))
+}
+
+// Script returns OP_0 <program>.
+func (a *Witness) Script() []byte {
+	return addrcodec.Script(addrcodec.Witness, a.program)
+}
+
+// Params returns the params of the network of a.
+func (a *Witness) Params() *nmcjson.Params {
+	return a.params
+}
+
+// newAddress returns the address of kind with the hash or witness program
+// data on the network of params.
+func newAddress(kind addrcodec.Kind, data []byte, params *nmcjson.Params) (Address, error) {
+	switch kind {
+	case addrcodec.PubKeyHash:
+		return NewPubKeyHash(data, params)
+	case addrcodec.ScriptHash:
+		return NewScriptHash(data, params)
+	}
+	return NewWitness(data, params)
+}
+
+// Decode parses addr as an address of the network of params.  An address of
+// another network returns ErrWrongNetwork, wrapped.  Since testnet and
+// regtest share their version bytes, their base58 addresses decode on either
+// network.
+func Decode(addr string, params *nmcjson.Params) (Address, error) {
+	if params == nil {
+		return nil, errNoParams
+	}
+	kind, data, err := addrcodec.Decode(addr, net(params))
+	if err != nil {
+		return nil, err
+	}
+	return newAddress(kind, data, params)
+}
+
+// FromScript returns the address of the network of params which is paid by
+// script, a P2PKH, P2SH or segwit version 0 output script.  When script is a
+// name output script, the address script following the name operation is
+// used, so the result is the owner of the name.
+func FromScript(script []byte, params *nmcjson.Params) (Address, error) {
+	if s, err := namescript.Decode(script); err == nil {
+		script = s.AddressScript
+	}
+	kind, data, err := addrcodec.FromScript(script)
+	if err != nil {
+		return nil, err
+	}
+	return newAddress(kind, data, params)
+}
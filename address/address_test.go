@@ -0,0 +1,157 @@
+package address
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kefkius/nmcjson"
+)
+
+func TestDecode(t *testing.T) {
+	mainnet, testnet, regtest := &nmcjson.MainNetParams, &nmcjson.TestNet3Params, &nmcjson.RegTestParams
+	const (
+		hash    = "000102030405060708090a0b0c0d0e0f10111213"
+		program = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	)
+	tests := []struct {
+		name   string
+		addr   string
+		params *nmcjson.Params
+		typ    Address
+		script string
+	}{{
+		name:   "mainnet P2PKH",
+		addr:   "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu",
+		params: mainnet,
+		typ:    (*PubKeyHash)(nil),
+		script: "76a914" + hash + "88ac",
+	}, {
+		name:   "mainnet P2SH",
+		addr:   "6EPs1STNZh4rxbsgP93Zu4BeHF5n9dtECo",
+		params: mainnet,
+		typ:    (*ScriptHash)(nil),
+		script: "a914" + hash + "87",
+	}, {
+		name:   "mainnet P2WPKH",
+		addr:   "nc1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnjqsk4h",
+		params: mainnet,
+		typ:    (*Witness)(nil),
+		script: "0014" + hash,
+	}, {
+		name:   "testnet P2PKH",
+		addr:   "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth",
+		params: testnet,
+		typ:    (*PubKeyHash)(nil),
+		script: "76a914" + hash + "88ac",
+	}, {
+		name:   "testnet P2SH",
+		addr:   "2MsFFCK16VhsCcvPXruztdzzcTZEQCbNKjJ",
+		params: testnet,
+		typ:    (*ScriptHash)(nil),
+		script: "a914" + hash + "87",
+	}, {
+		name:   "testnet P2WPKH",
+		addr:   "tn1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysn94gs7l",
+		params: testnet,
+		typ:    (*Witness)(nil),
+		script: "0014" + hash,
+	}, {
+		name:   "regtest P2PKH",
+		addr:   "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth",
+		params: regtest,
+		typ:    (*PubKeyHash)(nil),
+		script: "76a914" + hash + "88ac",
+	}, {
+		name:   "regtest P2WSH",
+		addr:   "ncrt1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0s9fxzwm",
+		params: regtest,
+		typ:    (*Witness)(nil),
+		script: "0020" + program,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, err := Decode(test.addr, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := reflect.TypeOf(a), reflect.TypeOf(test.typ); got != want {
+				t.Fatalf("type: got %v, want %v", got, want)
+			}
+			if a.String() != test.addr {
+				t.Fatalf("String: got %s, want %s", a, test.addr)
+			}
+			if a.Params() != test.params {
+				t.Fatalf("Params: got %s, want %s", a.Params().Name, test.params.Name)
+			}
+			if got := hex.EncodeToString(a.Script()); got != test.script {
+				t.Fatalf("Script: got %s, want %s", got, test.script)
+			}
+
+			script, _ := hex.DecodeString(test.script)
+			from, err := FromScript(script, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from.String() != test.addr {
+				t.Fatalf("FromScript: got %s, want %s", from, test.addr)
+			}
+
+			// OP_NAME_UPDATE "d/x" "v" OP_2DROP OP_DROP <script>
+			nameScript := append([]byte{0x53, 3, 'd', '/', 'x', 1, 'v', 0x6d, 0x75}, script...)
+			from, err = FromScript(nameScript, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from.String() != test.addr {
+				t.Fatalf("FromScript of a name script: got %s, want %s", from, test.addr)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   string
+		params *nmcjson.Params
+		err    error
+	}{
+		{"testnet P2PKH on mainnet", "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth", &nmcjson.MainNetParams, ErrWrongNetwork},
+		{"mainnet P2SH on testnet", "6EPs1STNZh4rxbsgP93Zu4BeHF5n9dtECo", &nmcjson.TestNet3Params, ErrWrongNetwork},
+		{"testnet segwit on regtest", "tn1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysn94gs7l", &nmcjson.RegTestParams, ErrWrongNetwork},
+		{"bitcoin P2PKH", "1111111111111111111114oLvT2", &nmcjson.MainNetParams, ErrWrongNetwork},
+		{"bad checksum", "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncv", &nmcjson.MainNetParams, ErrUnknownFormat},
+		{"bad bech32 checksum", "nc1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnjqsk4j", &nmcjson.MainNetParams, ErrUnknownFormat},
+		{"garbage", "not an address", &nmcjson.MainNetParams, ErrUnknownFormat},
+		{"no params", "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu", nil, errNoParams},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decode(test.addr, test.params); !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestFromScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"empty", ""},
+		{"OP_RETURN", "6a0568656c6c6f"},
+		{"short P2PKH", "76a913000102030405060708090a0b0c0d0e0f10111288ac"},
+		{"bare name script", "5303642f7801766d75"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(test.script)
+			if _, err := FromScript(script, &nmcjson.MainNetParams); !errors.Is(err, ErrNonStandardScript) {
+				t.Fatalf("got %v, want %v", err, ErrNonStandardScript)
+			}
+		})
+	}
+}
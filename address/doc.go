@@ -0,0 +1,22 @@
+/*
+Package address encodes and decodes Namecoin addresses and converts them to
+and from output scripts.
+
+Addresses belong to the network of an nmcjson.Params: base58 P2PKH and P2SH
+addresses use its version bytes (mainnet P2PKH addresses start with N or M,
+and P2SH ones with 6), and segwit version 0 addresses are bech32 encoded
+with its human readable part (nc1 on mainnet, tn1 on testnet).  Decode
+parses an address of a network, and the New functions build one from a hash
+or witness program.
+
+Address.Script returns the script paying to an address, which is the script
+following the name operation in the outputs of the names it owns, as in
+
+	script, err := namescript.NewNameUpdate(name, value, addr.Script()).Encode()
+
+FromScript is the inverse.  Given a name output script it skips the name
+operation, so it tells which address owns a name output.
+
+The validators of nmcjson check addresses with the same codec.
+*/
+package address
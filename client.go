@@ -0,0 +1,431 @@
+package nmcjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/btcsuite/btcd/btcjson"
+)
+
+// ConnConfig describes the connection configuration parameters for a Client.
+type ConnConfig struct {
+	// Host is the host and port of the namecoind RPC server, e.g.
+	// "localhost:8336".
+	Host string
+
+	// User is the username to use to authenticate to the RPC server.
+	User string
+
+	// Pass is the passphrase to use to authenticate to the RPC server.
+	Pass string
+
+	// DisableTLS specifies whether transport layer security should be
+	// disabled.
+	DisableTLS bool
+
+	// HTTPClient is the HTTP client used to send requests.  When nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Params are the params of the network of the server, which set the
+	// default maxage of name_filter and the network of the addresses
+	// names are sent to.  When nil, MainNetParams are used.
+	Params *Params
+}
+
+// Client is a Namecoin JSON-RPC client which sends the commands registered
+// by Init and parses their replies into the matching result types.
+type Client struct {
+	id         uint64 // atomic, so must stay 64-bit aligned
+	config     *ConnConfig
+	url        string
+	httpClient *http.Client
+	params     *Params
+}
+
+// rawReply models the JSON-RPC reply envelope before the result is parsed.
+type rawReply struct {
+	Result json.RawMessage   `json:"result"`
+	Error  *btcjson.RPCError `json:"error"`
+	Id     interface{}       `json:"id"`
+}
+
+// NewClient creates a new Client using the given connection configuration.
+func NewClient(config *ConnConfig) (*Client, error) {
+	if config == nil || config.Host == "" {
+		return nil, errors.New("no host specified")
+	}
+	scheme := "https"
+	if config.DisableTLS {
+		scheme = "http"
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	params := config.Params
+	if params == nil {
+		params = &MainNetParams
+	}
+	Init()
+	return &Client{
+		config:     config,
+		url:        scheme + "://" + config.Host,
+		httpClient: httpClient,
+		params:     params,
+	}, nil
+}
+
+// NextID returns the next id to be used when sending a JSON-RPC message.
+func (c *Client) NextID() uint64 {
+	return atomic.AddUint64(&c.id, 1)
+}
+
+// optString returns a pointer to s, or nil when s is empty.
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// destOptions returns the options which send a name to toAddress, or nil
+// when toAddress is empty.
+func destOptions(toAddress string) *NameTxOptions {
+	if toAddress == "" {
+		return nil
+	}
+	return &NameTxOptions{DestAddress: toAddress}
+}
+
+// optInt returns a pointer to i, or nil when i is zero.
+func optInt(i int) *int {
+	if i == 0 {
+		return nil
+	}
+	return &i
+}
+
+// post sends cmd to the server and returns the HTTP response.  The caller
+// must close the response body.
+func (c *Client) post(ctx context.Context, cmd interface{}) (*http.Response, error) {
+	body, err := btcjson.MarshalCmd(btcjson.RpcVersion1, c.NextID(), cmd)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Close = true
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(c.config.User, c.config.Pass)
+	return c.httpClient.Do(req)
+}
+
+// nameScanCmd returns the name_scan command of Client.NameScan.
+func nameScanCmd(start string, max int, options *NameScanOptions) *NameScanCmd {
+	var startName *string
+	if start != "" || max != 0 {
+		startName = &start
+	}
+	return NewNameScanCmd(startName, optInt(max), options)
+}
+
+// nameFilterCmd returns the name_filter command of Client.NameFilter.
+func (c *Client) nameFilterCmd(regexp string, maxAge, from, nb int) *NameFilterCmd {
+	if maxAge == 0 {
+		maxAge = c.params.DefaultFilterMaxAge
+	}
+	return NewNameFilterCmd(&regexp, &maxAge, &from, &nb, nil)
+}
+
+// sendCmd sends cmd to the server, waits for the reply and parses its
+// result with parse.
+func (c *Client) sendCmd(ctx context.Context, cmd interface{}, parse func(json.RawMessage) (interface{}, error)) (interface{}, error) {
+	resp, err := c.post(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply rawReply
+	if err := json.Unmarshal(respBytes, &reply); err != nil {
+		return nil, fmt.Errorf("status code: %d, response: %q", resp.StatusCode, string(respBytes))
+	}
+	if reply.Error != nil {
+		return nil, NewRPCError(reply.Error.Code, reply.Error.Message)
+	}
+	return parse(reply.Result)
+}
+
+// NameNew sends a name_new command and returns the resulting txid and rand.
+func (c *Client) NameNew(ctx context.Context, name string) (*NameNewResult, error) {
+	return c.NameNewWithOptions(ctx, name, nil)
+}
+
+// NameNewWithOptions sends a name_new command with options, which may be nil,
+// and returns the resulting txid and rand.
+func (c *Client) NameNewWithOptions(ctx context.Context, name string, options *NameTxOptions) (*NameNewResult, error) {
+	cmd, err := NewNameNewCmd(name, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Validate(c.params); err != nil {
+		return nil, err
+	}
+	res, err := c.sendCmd(ctx, cmd, NameNewReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	result := res.(NameNewResult)
+	return &result, nil
+}
+
+// NameFirstUpdate sends a name_firstupdate command.  toAddress is omitted
+// from the command when empty.
+func (c *Client) NameFirstUpdate(ctx context.Context, name, rand, txid, value, toAddress string) (NameFirstUpdateResult, error) {
+	return c.NameFirstUpdateWithOptions(ctx, name, rand, txid, value, destOptions(toAddress))
+}
+
+// NameFirstUpdateWithOptions sends a name_firstupdate command with options,
+// which may be nil.
+func (c *Client) NameFirstUpdateWithOptions(ctx context.Context, name, rand, txid, value string, options *NameTxOptions) (NameFirstUpdateResult, error) {
+	cmd, err := NewNameFirstUpdateCmd(name, rand, txid, value, options)
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Validate(c.params); err != nil {
+		return "", err
+	}
+	res, err := c.sendCmd(ctx, cmd, NameFirstUpdateReplyParse)
+	if err != nil {
+		return "", err
+	}
+	return res.(NameFirstUpdateResult), nil
+}
+
+// NameUpdate sends a name_update command.  toAddress is omitted from the
+// command when empty.
+func (c *Client) NameUpdate(ctx context.Context, name, value, toAddress string) (NameUpdateResult, error) {
+	return c.NameUpdateWithOptions(ctx, name, value, destOptions(toAddress))
+}
+
+// NameUpdateWithOptions sends a name_update command with options, which may
+// be nil.
+func (c *Client) NameUpdateWithOptions(ctx context.Context, name, value string, options *NameTxOptions) (NameUpdateResult, error) {
+	cmd, err := NewNameUpdateCmd(name, value, options)
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Validate(c.params); err != nil {
+		return "", err
+	}
+	res, err := c.sendCmd(ctx, cmd, NameUpdateReplyParse)
+	if err != nil {
+		return "", err
+	}
+	return res.(NameUpdateResult), nil
+}
+
+// NameShow sends a name_show command and returns the current state of name.
+func (c *Client) NameShow(ctx context.Context, name string) (*NameShowResult, error) {
+	return c.NameShowWithOptions(ctx, name, nil)
+}
+
+// NameShowWithOptions sends a name_show command with options, which may be
+// nil, and returns the current state of name.
+func (c *Client) NameShowWithOptions(ctx context.Context, name string, options *NameShowOptions) (*NameShowResult, error) {
+	cmd := NewNameShowCmd(name, options)
+	res, err := c.sendCmd(ctx, cmd, NameShowReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	result := res.(NameShowResult)
+	return &result, nil
+}
+
+// NameHistory sends a name_history command and returns all values of name.
+func (c *Client) NameHistory(ctx context.Context, name string) ([]NameHistoryResult, error) {
+	return c.NameHistoryWithOptions(ctx, name, nil)
+}
+
+// NameHistoryWithOptions sends a name_history command with the encodings of
+// options, which may be nil, and returns all values of name.
+func (c *Client) NameHistoryWithOptions(ctx context.Context, name string, options *NameEncodingOptions) ([]NameHistoryResult, error) {
+	cmd := NewNameHistoryCmd(name, options)
+	res, err := c.sendCmd(ctx, cmd, NameHistoryReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]NameHistoryResult), nil
+}
+
+// NameList sends a name_list command and returns the wallet's names.  When
+// name is not empty, only that name is listed.
+func (c *Client) NameList(ctx context.Context, name string) ([]NameListResult, error) {
+	return c.NameListWithOptions(ctx, name, nil)
+}
+
+// NameListWithOptions sends a name_list command with the encodings of
+// options, which may be nil, and returns the wallet's names.  When name is
+// not empty, only that name is listed.
+func (c *Client) NameListWithOptions(ctx context.Context, name string, options *NameEncodingOptions) ([]NameListResult, error) {
+	cmd := NewNameListCmd(optString(name), options)
+	res, err := c.sendCmd(ctx, cmd, NameListReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]NameListResult), nil
+}
+
+// NamePending sends a name_pending command and returns the unconfirmed name
+// operations.  When name is not empty, only operations on that name are
+// returned.
+func (c *Client) NamePending(ctx context.Context, name string) ([]NamePendingResult, error) {
+	return c.NamePendingWithOptions(ctx, name, nil)
+}
+
+// NamePendingWithOptions sends a name_pending command with the encodings of
+// options, which may be nil, and returns the unconfirmed name operations.
+// When name is not empty, only operations on that name are returned.
+func (c *Client) NamePendingWithOptions(ctx context.Context, name string, options *NameEncodingOptions) ([]NamePendingResult, error) {
+	cmd := NewNamePendingCmd(optString(name), options)
+	res, err := c.sendCmd(ctx, cmd, NamePendingReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]NamePendingResult), nil
+}
+
+// NameRawTransaction sends a namerawtransaction command, which attaches op
+// to output vout of the raw transaction hexTx and returns the result.
+func (c *Client) NameRawTransaction(ctx context.Context, hexTx string, vout int, op NameOp) (*NameRawTransactionResult, error) {
+	cmd, err := NewNameRawTransactionCmd(hexTx, vout, op)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.sendCmd(ctx, cmd, NameRawTransactionReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	result := res.(NameRawTransactionResult)
+	return &result, nil
+}
+
+// NameScan sends a name_scan command, returning at most max names starting
+// at start.  A zero max uses the server default.
+func (c *Client) NameScan(ctx context.Context, start string, max int) ([]NameScanResult, error) {
+	return c.NameScanWithOptions(ctx, start, max, nil)
+}
+
+// NameScanWithOptions sends a name_scan command with options, which may be
+// nil, returning at most max names starting at start.  A zero max uses the
+// server default.
+func (c *Client) NameScanWithOptions(ctx context.Context, start string, max int, options *NameScanOptions) ([]NameScanResult, error) {
+	res, err := c.sendCmd(ctx, nameScanCmd(start, max, options), NameScanReplyParse)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]NameScanResult), nil
+}
+
+// NameFilter sends a name_filter command and returns the matching names.
+// A zero maxAge uses the DefaultFilterMaxAge of the Client params, and a zero
+// nb returns all results.
+func (c *Client) NameFilter(ctx context.Context, regexp string, maxAge, from, nb int) ([]NameFilterResult, error) {
+	cmd := c.nameFilterCmd(regexp, maxAge, from, nb)
+	res, err := c.sendCmd(ctx, cmd, cmd.ParseReply)
+	if err != nil {
+		return nil, err
+	}
+	return res.([]NameFilterResult), nil
+}
+
+// NameFilterStat sends a name_filter command requesting statistics, and
+// returns the number of names matching regexp which were updated in the last
+// maxAge blocks.  A zero maxAge uses the DefaultFilterMaxAge of the Client
+// params.
+func (c *Client) NameFilterStat(ctx context.Context, regexp string, maxAge int) (*NameFilterStatResult, error) {
+	cmd := c.nameFilterCmd(regexp, maxAge, 0, 0)
+	stat := NameFilterStat
+	cmd.Stat = &stat
+	res, err := c.sendCmd(ctx, cmd, cmd.ParseReply)
+	if err != nil {
+		return nil, err
+	}
+	r := res.(NameFilterStatResult)
+	return &r, nil
+}
+
+// GetBlockCount returns the number of blocks in the longest block chain.
+func (c *Client) GetBlockCount(ctx context.Context) (int64, error) {
+	cmd := btcjson.NewGetBlockCountCmd()
+	res, err := c.sendCmd(ctx, cmd, func(msg json.RawMessage) (interface{}, error) {
+		var count int64
+		err := json.Unmarshal(msg, &count)
+		return count, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.(int64), nil
+}
+
+// GetBlockHash returns the hash of the block at height in the longest block
+// chain.
+func (c *Client) GetBlockHash(ctx context.Context, height int64) (string, error) {
+	cmd := btcjson.NewGetBlockHashCmd(height)
+	res, err := c.sendCmd(ctx, cmd, func(msg json.RawMessage) (interface{}, error) {
+		var hash string
+		err := json.Unmarshal(msg, &hash)
+		return hash, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.(string), nil
+}
+
+// GetBlockHeaderVerbose returns the header of the block with the given hash.
+func (c *Client) GetBlockHeaderVerbose(ctx context.Context, hash string) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	verbose := true
+	cmd := btcjson.NewGetBlockHeaderCmd(hash, &verbose)
+	res, err := c.sendCmd(ctx, cmd, func(msg json.RawMessage) (interface{}, error) {
+		var header btcjson.GetBlockHeaderVerboseResult
+		err := json.Unmarshal(msg, &header)
+		return &header, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*btcjson.GetBlockHeaderVerboseResult), nil
+}
+
+// GetBlockVerboseTx returns the block with the given hash, including its
+// decoded transactions, so the name operations of their outputs can be read
+// from the scriptPubKey hex.
+func (c *Client) GetBlockVerboseTx(ctx context.Context, hash string) (*btcjson.GetBlockVerboseTxResult, error) {
+	verbosity := 2
+	cmd := btcjson.NewGetBlockCmd(hash, &verbosity)
+	res, err := c.sendCmd(ctx, cmd, func(msg json.RawMessage) (interface{}, error) {
+		var block btcjson.GetBlockVerboseTxResult
+		err := json.Unmarshal(msg, &block)
+		return &block, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*btcjson.GetBlockVerboseTxResult), nil
+}
@@ -2,11 +2,11 @@ package nmcjson
 
 // nmcHelpStrings contains the help messages for API calls.
 var nmcHelpStrings = map[string]string{
-	"name_new": `name_new "name"
+	"name_new": `name_new "name" [options]
 Pre-order a new name`,
-	"name_update": `name_update "name" "value" [toaddress]
+	"name_update": `name_update "name" "value" [options]
 Update and possibly transfer a name`,
-	"name_firstupdate": `name_firstupdate "name" "rand" [tx] "value" [toaddress]
+	"name_firstupdate": `name_firstupdate "name" "rand" "tx" "value" [options]
 Perform a first update after a name_new reservation.
 Note that the first update will go into a block 12 blocks after the name_new, at the soonest`,
 	"name_filter": `name_filter [regexp] [maxage=36000] [from=0] [nb=0] [stat]
@@ -15,13 +15,25 @@ Scan and filter names
 [maxage] : look in last [maxage] blocks
 [from] : show results from number [from]
 [nb] : show [nb] results, 0 means all
-[stat] : show some stats instead of results`,
-	"name_history": `name_history "identifier"
+[stat] : "stat" to show some stats instead of results`,
+	"name_history": `name_history "identifier" [options]
     List all name values of a name.`,
-	"name_list": `name_list [name]
+	"name_list": `name_list [name] [options]
     List my own names`,
-	"name_scan": `name_scan [start-identifier] [max-return=500]
+	"name_pending": `name_pending [name] [options]
+    List unconfirmed name operations in the mempool`,
+	"name_scan": `name_scan [start-identifier] [max-return=500] [options]
     Scan all identifiers, starting at start-identifier and returning a maximum number of entries`,
-	"name_show": `name_show "identifier"
+	"namerawtransaction": `namerawtransaction "hexstring" vout nameop
+    Add a name operation to output vout of a raw transaction.
+    nameop is {"op": "name_new"|"name_firstupdate"|"name_update", "name": ..., "value": ..., "rand": ...}`,
+	"name_show": `name_show "identifier" [options]
     Show values of a name`,
 }
+
+// MethodHelp returns the help message for the passed name command method.
+// The second return value is false if method is not a name command.
+func MethodHelp(method string) (string, bool) {
+	help, ok := nmcHelpStrings[method]
+	return help, ok
+}
@@ -0,0 +1,56 @@
+package nmcjson
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// RandLength is the length in bytes of the rands generated by NewRand, the
+// same as the rands of Namecoin Core.
+const RandLength = 20
+
+// LegacyRandLength is the length in bytes of the rands generated by legacy
+// namecoind.
+const LegacyRandLength = 8
+
+// ErrRandMismatch indicates a rand which does not open the commitment of a
+// name_new for the given name.
+var ErrRandMismatch = errors.New("rand does not match the name_new commitment")
+
+// NewRand returns a hex encoded rand of RandLength bytes from a
+// cryptographically secure source, for use as the salt of a name_new.
+func NewRand() (string, error) {
+	b := make([]byte, RandLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NameCommitment returns the Hash160(rand || name) commitment which a name_new
+// puts on chain, where name holds the raw bytes of the name and rand is hex
+// encoded.
+func NameCommitment(name, rand string) ([]byte, error) {
+	if err := validateRand(rand); err != nil {
+		return nil, err
+	}
+	b, _ := hex.DecodeString(rand)
+	return btcutil.Hash160(append(b, name...)), nil
+}
+
+// VerifyNameCommitment checks that rand and name open commitment, the hash
+// of a name_new output.  ErrRandMismatch is returned when they do not.
+func VerifyNameCommitment(name, rand string, commitment []byte) error {
+	hash, err := NameCommitment(name, rand)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, commitment) {
+		return ErrRandMismatch
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package nmcjson
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestNameCommitment(t *testing.T) {
+	const rand = "0011223344556677889900112233445566778899"
+	tests := []struct {
+		name string
+		rand string
+		hash string
+		err  bool
+	}{
+		{name: "d/example", rand: rand, hash: "d042d04aff0761fccfdecd09d8193b4f7ceaacb2"},
+		{name: "d/example", rand: rand + "00", err: true},
+		{name: "d/example", rand: rand[:2*LegacyRandLength], hash: "937b83e153cc463ad79ee4152c0cd9be0e097f84"},
+		{name: "d/example", rand: rand[:2*LegacyRandLength+2], err: true},
+		{name: "d/example", rand: "", err: true},
+		{name: "d/example", rand: "xyz", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name+" "+test.rand, func(t *testing.T) {
+			hash, err := NameCommitment(test.name, test.rand)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if got := hex.EncodeToString(hash); !test.err && got != test.hash {
+				t.Fatalf("got %s, want %s", got, test.hash)
+			}
+		})
+	}
+}
+
+func TestVerifyNameCommitment(t *testing.T) {
+	rand, err := NewRand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rand) != 2*RandLength {
+		t.Fatalf("NewRand: got %d hex digits, want %d", len(rand), 2*RandLength)
+	}
+	hash, err := NameCommitment("d/example", rand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewRand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		rand string
+		err  error
+	}{
+		{name: "d/example", rand: rand},
+		{name: "d/other", rand: rand, err: ErrRandMismatch},
+		{name: "d/example", rand: other, err: ErrRandMismatch},
+	}
+	for _, test := range tests {
+		if err := VerifyNameCommitment(test.name, test.rand, hash); !errors.Is(err, test.err) {
+			t.Fatalf("%s with %s: got %v, want %v", test.name, test.rand, err, test.err)
+		}
+	}
+}
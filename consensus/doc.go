@@ -0,0 +1,27 @@
+/*
+Package consensus applies name operations to a name database with the rules
+of the Namecoin consensus, without a node.
+
+A State follows the rules of the nmcjson.Params given to New.  It starts
+empty and is advanced one block at a time with ApplyBlock.  Each operation
+is checked as namecoind checks it:
+
+  - name_new records a commitment, Hash160(rand || name).
+  - name_firstupdate must spend a name_new which is at least
+    MinFirstUpdateDepth blocks deep and whose commitment it opens, and the
+    name must not be active.  A name which expired can be registered again.
+  - name_update must spend the current output of the name, which must not
+    have expired.
+  - names, values and rands must fit the consensus size limits.
+
+A block whose operations are not all valid is rejected as a whole and
+leaves the State unchanged.  Names expire NameExpirationDepth blocks after
+their last operation, as evaluated at the height of the check.
+
+The State is deterministic: it does no I/O and the same blocks always give
+the same names.  Its views are the nmcjson result types, so a State can
+check what a node reports (CheckNameShow), run simulations on a Clone, or
+serve as the engine of a test double.  BlockOps reads the operations of a
+block returned by getblock.
+*/
+package consensus
@@ -0,0 +1,373 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+var (
+	// ErrBlockOrder indicates a block which does not come after the
+	// blocks already applied.
+	ErrBlockOrder = errors.New("block does not follow the state")
+
+	// ErrNameNewNotFound indicates a name_firstupdate which does not
+	// spend an unspent name_new.
+	ErrNameNewNotFound = errors.New("name_firstupdate does not spend a name_new")
+
+	// ErrNameInputNotFound indicates a name_update which does not spend
+	// the current output of the name.
+	ErrNameInputNotFound = errors.New("name_update does not spend the name")
+)
+
+// Outpoint identifies a transaction output.
+type Outpoint struct {
+	Txid string
+	Vout int
+}
+
+// String returns the outpoint as txid:vout.
+func (o Outpoint) String() string {
+	return fmt.Sprintf("%s:%d", o.Txid, o.Vout)
+}
+
+// Op is a name operation of a transaction.
+type Op struct {
+	// Script is the name script of the output.
+	Script *namescript.Script
+
+	// Out is the output holding the name script.
+	Out Outpoint
+
+	// Inputs are the outputs spent by the transaction.  The name input,
+	// a name_new for name_firstupdate or the name for name_update, is
+	// found among them.
+	Inputs []Outpoint
+
+	// Address is the owner of the output, reported in the views of the
+	// name.
+	Address string
+}
+
+// record is one value of a name.
+type record struct {
+	value   []byte
+	out     Outpoint
+	address string
+	height  int64
+}
+
+// nameNew is an unspent name_new.
+type nameNew struct {
+	hash   []byte
+	height int64
+}
+
+// State is the name database after a sequence of blocks.
+type State struct {
+	params *nmcjson.Params
+	height int64
+	names  map[string][]record // oldest first
+	news   map[Outpoint]nameNew
+}
+
+// New returns an empty State before the genesis block, following the rules
+// of params.
+func New(params *nmcjson.Params) *State {
+	return &State{
+		params: params,
+		height: -1,
+		names:  make(map[string][]record),
+		news:   make(map[Outpoint]nameNew),
+	}
+}
+
+// Height returns the height of the last applied block, or -1 if none was.
+func (s *State) Height() int64 {
+	return s.height
+}
+
+// Clone returns an independent copy of s.
+func (s *State) Clone() *State {
+	c := &State{
+		params: s.params,
+		height: s.height,
+		names:  make(map[string][]record, len(s.names)),
+		news:   make(map[Outpoint]nameNew, len(s.news)),
+	}
+	for name, records := range s.names {
+		c.names[name] = append([]record(nil), records...)
+	}
+	for out, n := range s.news {
+		c.news[out] = n
+	}
+	return c
+}
+
+// change undoes one effect of an operation.
+type change func(s *State)
+
+// ApplyBlock applies the name operations of the block at height, in the
+// order they appear in the block.  Blocks without name operations may be
+// skipped, but height must be greater than that of the last block applied.
+// If an operation is invalid the error names it, and s is left unchanged.
+func (s *State) ApplyBlock(height int64, ops []Op) error {
+	if height <= s.height {
+		return fmt.Errorf("%w: height %d, the state is at %d", ErrBlockOrder, height, s.height)
+	}
+	var undo []change
+	for i, op := range ops {
+		if err := s.apply(height, op, &undo); err != nil {
+			for j := len(undo) - 1; j >= 0; j-- {
+				undo[j](s)
+			}
+			return fmt.Errorf("op %d (%v) of block %d: %w", i, op.Out, height, err)
+		}
+	}
+	s.height = height
+	return nil
+}
+
+// apply applies op at height and appends the changes it made to undo.
+func (s *State) apply(height int64, op Op, undo *[]change) error {
+	if op.Script == nil {
+		return errors.New("no name script")
+	}
+	if err := op.Script.Validate(); err != nil {
+		return err
+	}
+	switch op.Script.Op {
+	case namescript.OpNameNew:
+		prev, existed := s.news[op.Out]
+		s.news[op.Out] = nameNew{hash: op.Script.Hash, height: height}
+		*undo = append(*undo, func(s *State) {
+			if existed {
+				s.news[op.Out] = prev
+			} else {
+				delete(s.news, op.Out)
+			}
+		})
+		return nil
+
+	case namescript.OpNameFirstUpdate:
+		name := string(op.Script.Name)
+		in, n, ok := s.spentNameNew(op.Inputs)
+		if !ok {
+			return ErrNameNewNotFound
+		}
+		if height-n.height < s.params.MinFirstUpdateDepth {
+			return fmt.Errorf("%w: the name_new is %d blocks deep, %d are required",
+				nmcjson.ErrFirstUpdateTooEarly, height-n.height, s.params.MinFirstUpdateDepth)
+		}
+		if err := nmcjson.VerifyNameCommitment(name, hex.EncodeToString(op.Script.Rand), n.hash); err != nil {
+			return err
+		}
+		if records := s.names[name]; len(records) > 0 && !s.expired(records[len(records)-1], height) {
+			return fmt.Errorf("%w: %q", nmcjson.ErrNameAlreadyRegistered, name)
+		}
+		delete(s.news, in)
+		*undo = append(*undo, func(s *State) { s.news[in] = n })
+
+	case namescript.OpNameUpdate:
+		name := string(op.Script.Name)
+		records := s.names[name]
+		if len(records) == 0 {
+			return fmt.Errorf("%w: %q", nmcjson.ErrNameNotFound, name)
+		}
+		current := records[len(records)-1]
+		if s.expired(current, height) {
+			return fmt.Errorf("%w: %q", nmcjson.ErrNameExpired, name)
+		}
+		if !spends(op.Inputs, current.out) {
+			return fmt.Errorf("%w: %q is at %v", ErrNameInputNotFound, name, current.out)
+		}
+	}
+
+	name := string(op.Script.Name)
+	s.names[name] = append(s.names[name], record{
+		value:   op.Script.Value,
+		out:     op.Out,
+		address: op.Address,
+		height:  height,
+	})
+	*undo = append(*undo, func(s *State) {
+		records := s.names[name]
+		if len(records) == 1 {
+			delete(s.names, name)
+		} else {
+			s.names[name] = records[:len(records)-1]
+		}
+	})
+	return nil
+}
+
+// spentNameNew returns the first of inputs which is an unspent name_new.
+func (s *State) spentNameNew(inputs []Outpoint) (Outpoint, nameNew, bool) {
+	for _, in := range inputs {
+		if n, ok := s.news[in]; ok {
+			return in, n, true
+		}
+	}
+	return Outpoint{}, nameNew{}, false
+}
+
+// spends reports whether out is one of inputs.
+func spends(inputs []Outpoint, out Outpoint) bool {
+	for _, in := range inputs {
+		if in == out {
+			return true
+		}
+	}
+	return false
+}
+
+// expired reports whether r has expired at height.
+func (s *State) expired(r record, height int64) bool {
+	return s.params.IsExpired(r.height, height)
+}
+
+// NameShow returns the current value of the raw name, as name_show does at
+// the height of s.  Expired names are returned with Expired set, and names
+// which never existed return nmcjson.ErrNameNotFound.
+func (s *State) NameShow(name string) (*nmcjson.NameShowResult, error) {
+	records := s.names[name]
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: %q", nmcjson.ErrNameNotFound, name)
+	}
+	result := s.showResult(name, records[len(records)-1])
+	return &result, nil
+}
+
+// NameHistory returns every value of the raw name, oldest first, as
+// name_history does.
+func (s *State) NameHistory(name string) ([]nmcjson.NameHistoryResult, error) {
+	records := s.names[name]
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: %q", nmcjson.ErrNameNotFound, name)
+	}
+	results := make([]nmcjson.NameHistoryResult, 0, len(records))
+	for _, r := range records {
+		results = append(results, nmcjson.NameHistoryResult(s.showResult(name, r)))
+	}
+	return results, nil
+}
+
+// NameScan returns at most max names, in order, starting at the raw name
+// start, as name_scan does.  A max of zero returns every name.
+func (s *State) NameScan(start string, max int) []nmcjson.NameScanResult {
+	names := make([]string, 0, len(s.names))
+	for name := range s.names {
+		if name >= start {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if max > 0 && len(names) > max {
+		names = names[:max]
+	}
+	results := make([]nmcjson.NameScanResult, 0, len(names))
+	for _, name := range names {
+		records := s.names[name]
+		results = append(results, nmcjson.NameScanResult(s.showResult(name, records[len(records)-1])))
+	}
+	return results
+}
+
+// showResult returns the name_show view of r at the height of s.
+func (s *State) showResult(name string, r record) nmcjson.NameShowResult {
+	return nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{
+			Name:  name,
+			Value: string(r.value),
+		},
+		Txid:      r.out.Txid,
+		Vout:      r.out.Vout,
+		Address:   r.address,
+		Height:    r.height,
+		ExpiresIn: s.params.ExpiresIn(r.height, s.height),
+		Expired:   s.expired(r, s.height),
+	}
+}
+
+// ErrMismatch indicates a name_show result which disagrees with the state.
+var ErrMismatch = errors.New("name_show result does not match the state")
+
+// CheckNameShow checks r, a name_show result of a node at the height of s,
+// against the state of its name.  The name and value of r are decoded with
+// its encodings, and its address is only compared when both are known.
+// ErrMismatch is returned, wrapped with the first differing field, when they
+// disagree.
+func (s *State) CheckNameShow(r *nmcjson.NameShowResult) error {
+	name, err := r.NameBytes()
+	if err != nil {
+		return err
+	}
+	value, err := r.ValueBytes()
+	if err != nil {
+		return err
+	}
+	want, err := s.NameShow(string(name))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	mismatch := func(field string, got, want interface{}) error {
+		return fmt.Errorf("%w: %q has %s %v, expected %v", ErrMismatch, name, field, got, want)
+	}
+	switch {
+	case string(value) != want.Value:
+		return mismatch("value", fmt.Sprintf("%q", value), fmt.Sprintf("%q", want.Value))
+	case r.Txid != want.Txid:
+		return mismatch("txid", r.Txid, want.Txid)
+	case r.Vout != want.Vout:
+		return mismatch("vout", r.Vout, want.Vout)
+	case r.Height != want.Height:
+		return mismatch("height", r.Height, want.Height)
+	case r.ExpiresIn != want.ExpiresIn:
+		return mismatch("expires_in", r.ExpiresIn, want.ExpiresIn)
+	case r.Expired != want.Expired:
+		return mismatch("expired", r.Expired, want.Expired)
+	case r.Address != "" && want.Address != "" && r.Address != want.Address:
+		return mismatch("address", r.Address, want.Address)
+	}
+	return nil
+}
+
+// BlockOps returns the name operations of block, a getblock result with
+// verbosity 2, in block order.  Addresses are those of the network of params.
+func BlockOps(block *btcjson.GetBlockVerboseTxResult, params *nmcjson.Params) []Op {
+	var ops []Op
+	for _, tx := range block.Tx {
+		var inputs []Outpoint
+		for _, in := range tx.Vin {
+			if !in.IsCoinBase() {
+				inputs = append(inputs, Outpoint{Txid: in.Txid, Vout: int(in.Vout)})
+			}
+		}
+		for _, out := range tx.Vout {
+			script, err := hex.DecodeString(out.ScriptPubKey.Hex)
+			if err != nil {
+				continue
+			}
+			s, err := namescript.Decode(script)
+			if err != nil {
+				continue
+			}
+			op := Op{
+				Script: s,
+				Out:    Outpoint{Txid: tx.Txid, Vout: int(out.N)},
+				Inputs: inputs,
+			}
+			if addr, err := address.FromScript(s.AddressScript, params); err == nil {
+				op.Address = addr.String()
+			}
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
@@ -0,0 +1,340 @@
+package consensus
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+// testRand is the rand of every name_new of the tests.
+const testRand = "0011223344556677889900112233445566778899"
+
+// out returns the first output of the transaction txid.
+func out(txid string) Outpoint {
+	return Outpoint{Txid: txid, Vout: 0}
+}
+
+// newOp returns the name_new of name in the transaction txid.
+func newOp(txid, name string) Op {
+	hash, err := nmcjson.NameCommitment(name, testRand)
+	if err != nil {
+		panic(err)
+	}
+	return Op{Script: namescript.NewNameNew(hash, nil), Out: out(txid)}
+}
+
+// firstUpdateOp returns the name_firstupdate of name in the transaction txid
+// spending in.
+func firstUpdateOp(txid, name, value string, in Outpoint) Op {
+	rand, _ := hex.DecodeString(testRand)
+	return Op{
+		Script: namescript.NewNameFirstUpdate([]byte(name), rand, []byte(value), nil),
+		Out:    out(txid),
+		Inputs: []Outpoint{{Txid: "funding", Vout: 1}, in},
+	}
+}
+
+// updateOp returns the name_update of name in the transaction txid spending
+// in.
+func updateOp(txid, name, value string, in Outpoint) Op {
+	return Op{
+		Script: namescript.NewNameUpdate([]byte(name), []byte(value), nil),
+		Out:    out(txid),
+		Inputs: []Outpoint{in},
+	}
+}
+
+// block is the height and operations of a block to apply.
+type block struct {
+	height int64
+	ops    []Op
+}
+
+func TestApplyBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []block
+		err    error // of the last block
+		value  string
+	}{{
+		name:   "register",
+		blocks: []block{{1, []Op{newOp("new", "d/x")}}, {13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}}},
+		value:  "a",
+	}, {
+		name:   "firstupdate too early",
+		blocks: []block{{1, []Op{newOp("new", "d/x")}}, {12, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}}},
+		err:    nmcjson.ErrFirstUpdateTooEarly,
+	}, {
+		name:   "commitment of another name",
+		blocks: []block{{1, []Op{newOp("new", "d/y")}}, {13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}}},
+		err:    nmcjson.ErrRandMismatch,
+	}, {
+		name:   "no name_new",
+		blocks: []block{{1, []Op{newOp("new", "d/x")}}, {13, []Op{firstUpdateOp("first", "d/x", "a", out("other"))}}},
+		err:    ErrNameNewNotFound,
+	}, {
+		name: "name_new spent twice",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{14, []Op{firstUpdateOp("again", "d/x", "b", out("new"))}},
+		},
+		err: ErrNameNewNotFound,
+	}, {
+		name: "already registered",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x"), newOp("new2", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{14, []Op{firstUpdateOp("first2", "d/x", "b", out("new2"))}},
+		},
+		err: nmcjson.ErrNameAlreadyRegistered,
+	}, {
+		name: "registered again before expiry",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x"), newOp("new2", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{42, []Op{firstUpdateOp("first2", "d/x", "b", out("new2"))}},
+		},
+		err: nmcjson.ErrNameAlreadyRegistered,
+	}, {
+		name: "registered again after expiry",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x"), newOp("new2", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{43, []Op{firstUpdateOp("first2", "d/x", "b", out("new2"))}},
+		},
+		value: "b",
+	}, {
+		name: "update",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{14, []Op{updateOp("upd", "d/x", "b", out("first")), updateOp("upd2", "d/x", "c", out("upd"))}},
+		},
+		value: "c",
+	}, {
+		name: "update of another output",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{14, []Op{updateOp("upd", "d/x", "b", out("new"))}},
+		},
+		err: ErrNameInputNotFound,
+	}, {
+		name: "update of an expired name",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+			{43, []Op{updateOp("upd", "d/x", "b", out("first"))}},
+		},
+		err: nmcjson.ErrNameExpired,
+	}, {
+		name:   "update of an unknown name",
+		blocks: []block{{1, []Op{updateOp("upd", "d/x", "b", out("first"))}}},
+		err:    nmcjson.ErrNameNotFound,
+	}, {
+		name: "value too long",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{firstUpdateOp("first", "d/x", strings.Repeat("v", nmcjson.MaxValueLength+1), out("new"))}},
+		},
+		err: nmcjson.ErrValueTooLong,
+	}, {
+		name: "block out of order",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{1, []Op{newOp("new2", "d/y")}},
+		},
+		err: ErrBlockOrder,
+	}, {
+		name: "rejected block is undone",
+		blocks: []block{
+			{1, []Op{newOp("new", "d/x")}},
+			{13, []Op{
+				firstUpdateOp("first", "d/x", "a", out("new")),
+				newOp("new2", "d/y"),
+				updateOp("upd", "d/y", "b", out("first")),
+			}},
+		},
+		err: nmcjson.ErrNameNotFound,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New(&nmcjson.RegTestParams)
+			last := len(test.blocks) - 1
+			for _, b := range test.blocks[:last] {
+				if err := s.ApplyBlock(b.height, b.ops); err != nil {
+					t.Fatalf("block %d: %v", b.height, err)
+				}
+			}
+			before := s.Clone()
+			b := test.blocks[last]
+			err := s.ApplyBlock(b.height, b.ops)
+			if !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if err != nil {
+				if !reflect.DeepEqual(s, before) {
+					t.Fatal("the rejected block changed the state")
+				}
+				return
+			}
+			if s.Height() != b.height {
+				t.Fatalf("height: got %d, want %d", s.Height(), b.height)
+			}
+			show, err := s.NameShow("d/x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if show.Value != test.value {
+				t.Fatalf("value: got %q, want %q", show.Value, test.value)
+			}
+		})
+	}
+}
+
+func TestViews(t *testing.T) {
+	s := New(&nmcjson.RegTestParams)
+	blocks := []block{
+		{1, []Op{newOp("new-a", "d/a"), newOp("new-b", "d/b")}},
+		{13, []Op{firstUpdateOp("first-a", "d/a", "a1", out("new-a"))}},
+		{14, []Op{firstUpdateOp("first-b", "d/b", "b1", out("new-b"))}},
+		{20, []Op{updateOp("upd-a", "d/a", "a2", out("first-a"))}},
+		{44, nil},
+	}
+	for _, b := range blocks {
+		if err := s.ApplyBlock(b.height, b.ops); err != nil {
+			t.Fatalf("block %d: %v", b.height, err)
+		}
+	}
+
+	a, err := s.NameShow("d/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "d/a", Value: "a2"}, Txid: "upd-a", Height: 20, ExpiresIn: 6}
+	if *a != want {
+		t.Fatalf("NameShow d/a: got %+v, want %+v", *a, want)
+	}
+	b, err := s.NameShow("d/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Expired || b.ExpiresIn != 0 {
+		t.Fatalf("NameShow d/b: got expired %v in %d, want expired", b.Expired, b.ExpiresIn)
+	}
+	if _, err := s.NameShow("d/c"); !errors.Is(err, nmcjson.ErrNameNotFound) {
+		t.Fatalf("NameShow d/c: got %v, want %v", err, nmcjson.ErrNameNotFound)
+	}
+
+	history, err := s.NameHistory("d/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []string
+	for _, h := range history {
+		values = append(values, h.Value)
+	}
+	if !reflect.DeepEqual(values, []string{"a1", "a2"}) {
+		t.Fatalf("NameHistory d/a: got %q, want %q", values, []string{"a1", "a2"})
+	}
+
+	for _, test := range []struct {
+		start string
+		max   int
+		names []string
+	}{
+		{"", 0, []string{"d/a", "d/b"}},
+		{"d/a0", 0, []string{"d/b"}},
+		{"", 1, []string{"d/a"}},
+	} {
+		var names []string
+		for _, r := range s.NameScan(test.start, test.max) {
+			names = append(names, r.Name)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Fatalf("NameScan %q %d: got %q, want %q", test.start, test.max, names, test.names)
+		}
+	}
+}
+
+func TestCheckNameShow(t *testing.T) {
+	s := New(&nmcjson.RegTestParams)
+	blocks := []block{
+		{1, []Op{newOp("new", "d/x")}},
+		{13, []Op{firstUpdateOp("first", "d/x", "a", out("new"))}},
+		{20, nil},
+	}
+	for _, b := range blocks {
+		if err := s.ApplyBlock(b.height, b.ops); err != nil {
+			t.Fatalf("block %d: %v", b.height, err)
+		}
+	}
+	good := nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "d/x", Value: "a"}, Txid: "first", Height: 13, ExpiresIn: 23}
+	tests := []struct {
+		name   string
+		modify func(r *nmcjson.NameShowResult)
+		err    error
+	}{
+		{"matching", func(r *nmcjson.NameShowResult) {}, nil},
+		{"hex encoded", func(r *nmcjson.NameShowResult) {
+			r.Name, r.NameEncoding = "642f78", nmcjson.EncodingHex
+			r.Value, r.ValueEncoding = "61", nmcjson.EncodingHex
+		}, nil},
+		{"value", func(r *nmcjson.NameShowResult) { r.Value = "b" }, ErrMismatch},
+		{"txid", func(r *nmcjson.NameShowResult) { r.Txid = "other" }, ErrMismatch},
+		{"expires_in", func(r *nmcjson.NameShowResult) { r.ExpiresIn = 24 }, ErrMismatch},
+		{"unknown name", func(r *nmcjson.NameShowResult) { r.Name = "d/y" }, ErrMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := good
+			test.modify(&r)
+			if err := s.CheckNameShow(&r); !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestBlockOps(t *testing.T) {
+	p2pkh := "76a914000102030405060708090a0b0c0d0e0f1011121388ac"
+	update := "53" + "03" + "642f78" + "01" + "61" + "6d75" + p2pkh
+	block := &btcjson.GetBlockVerboseTxResult{
+		Tx: []btcjson.TxRawResult{{
+			Txid: "coinbase",
+			Vin:  []btcjson.Vin{{Coinbase: "03"}},
+			Vout: []btcjson.Vout{{N: 0, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: p2pkh}}},
+		}, {
+			Txid: "upd",
+			Vin:  []btcjson.Vin{{Txid: "first", Vout: 1}, {Txid: "funding", Vout: 0}},
+			Vout: []btcjson.Vout{
+				{N: 0, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: p2pkh}},
+				{N: 1, ScriptPubKey: btcjson.ScriptPubKeyResult{Hex: update}},
+			},
+		}},
+	}
+	ops := BlockOps(block, &nmcjson.MainNetParams)
+	if len(ops) != 1 {
+		t.Fatalf("got %d ops, want 1", len(ops))
+	}
+	op := ops[0]
+	if op.Script.Op != namescript.OpNameUpdate || string(op.Script.Name) != "d/x" || string(op.Script.Value) != "a" {
+		t.Fatalf("script: got %v", op.Script)
+	}
+	if want := (Outpoint{Txid: "upd", Vout: 1}); op.Out != want {
+		t.Fatalf("out: got %v, want %v", op.Out, want)
+	}
+	if want := []Outpoint{{"first", 1}, {"funding", 0}}; !reflect.DeepEqual(op.Inputs, want) {
+		t.Fatalf("inputs: got %v, want %v", op.Inputs, want)
+	}
+	if want := "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu"; op.Address != want {
+		t.Fatalf("address: got %s, want %s", op.Address, want)
+	}
+}
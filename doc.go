@@ -1,9 +1,25 @@
 /*
 Package nmcjson extends btcjson with Namecoin-specific JSON-RPC API calls.
 
-Each commands requires a RawCmdParser and ReplyParser, as per btcjson. These functions are implemented as <command>FromRaw() and <command>ReplyParse(), respectively.
+Each command is a plain struct registered with btcjson.RegisterCmd, so
+btcjson.MarshalCmd, btcjson.UnmarshalCmd and btcjson.CmdMethod work with it.
+Optional parameters are pointer fields. Replies are parsed with the
+<command>ReplyParse() functions. The reply to name_filter depends on its stat
+parameter, so NameFilterCmd.ParseReply picks the parser matching the command.
 
-Usage
+Every command has a Validate method which checks it against the consensus
+limits on names, values and rands and, for commands which send a name, that
+the addresses are valid on a Namecoin network. The constructors of
+name_new, name_firstupdate and name_update validate the command they return;
+build the struct directly to skip the checks.
+
+The rules of a network are described by a Params: MainNetParams,
+TestNet3Params or RegTestParams, or the result of NetworkParams. There is no
+package-wide network: ConnConfig.Params selects the network of a Client,
+which sets its name_filter default and the network of the addresses it
+sends names to, and the subpackages take the params they need explicitly.
+
+# Usage
 
 Init() must be called before using any calls in nmcjson, as this function registers commands with btcjson.
 */
@@ -0,0 +1,15 @@
+/*
+Package domain parses and encodes the values of Namecoin d/ names.
+
+The value of a d/ name is a Domain Name Object: a JSON object whose fields
+describe the records of the domain (ip, ip6, ns, ds, tls, tor, i2p, alias,
+translate, email, info), the subdomains in its map, and the names it
+imports.  Parse decodes a value into a Domain and checks every field,
+returning an Errors list whose entries are addressed by their path in the
+object, such as map["www"].ip[1].
+
+Domain.Value encodes a Domain back into the most compact JSON accepted by
+Parse, checked against the size limit of a name_update value.  Fields not
+covered by the model are kept in Domain.Extra so they survive a round trip.
+*/
+package domain
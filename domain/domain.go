@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// NamePrefix is the namespace prefix of domain names.
+const NamePrefix = "d/"
+
+// Domain models a Domain Name Object, the value of a d/ name or of a
+// subdomain in the map of another Domain.
+type Domain struct {
+	// IP and IP6 are the IPv4 and IPv6 addresses of the domain.
+	IP  []string
+	IP6 []string
+
+	// NS are the host names of the domain's name servers.
+	NS []string
+
+	// DS are the DNSSEC delegation signer records of the domain.
+	DS []DS
+
+	// TLS are the TLSA records pinning the domain's certificates.
+	TLS []TLSA
+
+	// Tor is the .onion address of the domain.
+	Tor string
+
+	// I2P describes the domain's I2P destination.
+	I2P *I2P
+
+	// Alias makes the domain an alias of another domain name, like a
+	// CNAME record.
+	Alias string
+
+	// Translate makes the subdomains of the domain aliases of the
+	// subdomains of another domain name, like a DNAME record.
+	Translate string
+
+	// Map holds the subdomains by label.  The label "" applies to the
+	// domain itself and "*" to any label without an entry.
+	Map map[string]*Domain
+
+	// Import lists the names whose values are merged into the domain.
+	Import []Import
+
+	// Email is the contact address of the domain's owner.
+	Email string
+
+	// Info is free-form JSON about the domain.
+	Info json.RawMessage
+
+	// Extra holds the fields not covered by the model, so they are kept
+	// when the domain is encoded again.
+	Extra map[string]json.RawMessage
+}
+
+// DS is a DNSSEC delegation signer record, encoded as the array
+// [keyTag, algorithm, digestType, base64 digest].
+type DS struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+// MarshalJSON encodes d as an array.
+func (d DS) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		d.KeyTag, d.Algorithm, d.DigestType,
+		base64.StdEncoding.EncodeToString(d.Digest),
+	})
+}
+
+// TLSA is a TLSA record, encoded as the array
+// [usage, selector, matchingType, base64 data].
+type TLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// MarshalJSON encodes t as an array.
+func (t TLSA) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{
+		t.Usage, t.Selector, t.MatchingType,
+		base64.StdEncoding.EncodeToString(t.Data),
+	})
+}
+
+// I2P describes an I2P destination.
+type I2P struct {
+	Destination string `json:"destination,omitempty"`
+	Name        string `json:"name,omitempty"`
+	B32         string `json:"b32,omitempty"`
+}
+
+// Import is a name whose value is merged into a domain.  When Subdomain is
+// set, only that subdomain of the imported value is merged.
+type Import struct {
+	Name      string
+	Subdomain string
+}
+
+// MarshalJSON encodes i as a string, or as a [name, subdomain] array when
+// Subdomain is set.
+func (i Import) MarshalJSON() ([]byte, error) {
+	if i.Subdomain == "" {
+		return json.Marshal(i.Name)
+	}
+	return json.Marshal([]string{i.Name, i.Subdomain})
+}
+
+// MarshalJSON encodes d compactly.  Lists with a single string are encoded
+// as that string, and empty fields are omitted.
+func (d *Domain) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	field := func(key string, v interface{}) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(b)
+		return nil
+	}
+
+	type entry struct {
+		key   string
+		value interface{}
+		set   bool
+	}
+	entries := []entry{
+		{"ip", stringList(d.IP), len(d.IP) > 0},
+		{"ip6", stringList(d.IP6), len(d.IP6) > 0},
+		{"ns", stringList(d.NS), len(d.NS) > 0},
+		{"ds", d.DS, len(d.DS) > 0},
+		{"tls", d.TLS, len(d.TLS) > 0},
+		{"tor", d.Tor, d.Tor != ""},
+		{"i2p", d.I2P, d.I2P != nil},
+		{"alias", d.Alias, d.Alias != ""},
+		{"translate", d.Translate, d.Translate != ""},
+		{"map", d.Map, len(d.Map) > 0},
+		{"import", importList(d.Import), len(d.Import) > 0},
+		{"email", d.Email, d.Email != ""},
+		{"info", d.Info, len(d.Info) > 0},
+	}
+	for _, e := range entries {
+		if !e.set {
+			continue
+		}
+		if err := field(e.key, e.value); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(d.Extra))
+	for k := range d.Extra {
+		if _, ok := parsers[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := field(k, d.Extra[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON parses b with Parse.
+func (d *Domain) UnmarshalJSON(b []byte) error {
+	parsed, err := ParseBytes(b)
+	if err != nil {
+		return err
+	}
+	*d = *parsed
+	return nil
+}
+
+// Validate checks every field of d, as Parse does for a value.
+func (d *Domain) Validate() error {
+	b, err := d.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = ParseBytes(b)
+	return err
+}
+
+// Value validates d and returns it as compact JSON for use as the value of
+// a name_update.  nmcjson.ErrValueTooLong is returned, wrapped, when the
+// encoded value is longer than nmcjson.MaxRPCValueLength, the limit of the
+// name RPCs.
+func (d *Domain) Value() (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+	b, err := d.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if len(b) > nmcjson.MaxRPCValueLength {
+		return "", fmt.Errorf("%w: %d bytes, the maximum is %d",
+			nmcjson.ErrValueTooLong, len(b), nmcjson.MaxRPCValueLength)
+	}
+	return string(b), nil
+}
+
+// stringList returns l, or its only element when it has one.
+func stringList(l []string) interface{} {
+	if len(l) == 1 {
+		return l[0]
+	}
+	return l
+}
+
+// importList returns l, or its only element when it has one without a
+// subdomain.  A lone [name, subdomain] pair is kept in a list, since it would
+// otherwise read as two names.
+func importList(l []Import) interface{} {
+	if len(l) == 1 && l[0].Subdomain == "" {
+		return l[0]
+	}
+	return l
+}
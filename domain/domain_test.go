@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kefkius/nmcjson"
+)
+
+func TestParse(t *testing.T) {
+	sha256 := make([]byte, 32)
+	for i := range sha256 {
+		sha256[i] = byte(i)
+	}
+	value := `{
+		"ip": ["192.0.2.1", "192.0.2.2"],
+		"ip6": "2001:db8::1",
+		"ns": ["ns1.example.com.", "ns2.example.com"],
+		"ds": [[31337, 8, 2, "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="]],
+		"tls": [[3, 1, 1, "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="]],
+		"tor": "abcdefghijklmnop.onion",
+		"translate": "example.bit.",
+		"map": {
+			"www": {"alias": "@"},
+			"mail": "192.0.2.3",
+			"*": {"ip6": ["2001:db8::2"]}
+		},
+		"import": ["d/other", ["dd/shared", "www"]],
+		"email": "hostmaster@example.com",
+		"info": {"owner": "example"},
+		"x-custom": [1, 2]
+	}`
+	want := &Domain{
+		IP:        []string{"192.0.2.1", "192.0.2.2"},
+		IP6:       []string{"2001:db8::1"},
+		NS:        []string{"ns1.example.com.", "ns2.example.com"},
+		DS:        []DS{{KeyTag: 31337, Algorithm: 8, DigestType: 2, Digest: sha256}},
+		TLS:       []TLSA{{Usage: 3, Selector: 1, MatchingType: 1, Data: sha256}},
+		Tor:       "abcdefghijklmnop.onion",
+		Translate: "example.bit.",
+		Map: map[string]*Domain{
+			"www":  {Alias: "@"},
+			"mail": {IP: []string{"192.0.2.3"}},
+			"*":    {IP6: []string{"2001:db8::2"}},
+		},
+		Import: []Import{{Name: "d/other"}, {Name: "dd/shared", Subdomain: "www"}},
+		Email:  "hostmaster@example.com",
+		Info:   json.RawMessage(`{"owner":"example"}`),
+		Extra:  map[string]json.RawMessage{"x-custom": json.RawMessage(`[1,2]`)},
+	}
+	got, err := Parse(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Fatalf("got %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		paths []string
+	}{
+		{"not an object", `["192.0.2.1"]`, []string{""}},
+		{"bad IPv4", `{"ip": "192.0.2"}`, []string{"ip"}},
+		{"IPv6 in ip", `{"ip": ["192.0.2.1", "2001:db8::1"]}`, []string{"ip[1]"}},
+		{"IPv4 in ip6", `{"ip6": "192.0.2.1"}`, []string{"ip6"}},
+		{"bad name server", `{"ns": ["ns1.example.com", "-ns2.example.com"]}`, []string{"ns[1]"}},
+		{"ds key tag", `{"ds": [[70000, 8, 2, "AAEC"]]}`, []string{"ds[0][0]"}},
+		{"ds digest", `{"ds": [[1, 8, 2, "not base64"]]}`, []string{"ds[0][3]"}},
+		{"ds arity", `{"ds": [[1, 8, 2]]}`, []string{"ds[0]"}},
+		{"tls digest length", `{"tls": [[3, 1, 1, "AAEC"]]}`, []string{"tls[0][3]"}},
+		{"tls usage", `{"tls": [[4, 1, 0, "AAEC"]]}`, []string{"tls[0][0]"}},
+		{"onion", `{"tor": "example.onion"}`, []string{"tor"}},
+		{"i2p b32", `{"i2p": {"b32": "example.b32.i2p"}}`, []string{"i2p.b32"}},
+		{"i2p field", `{"i2p": {"host": "example.i2p"}}`, []string{"i2p"}},
+		{"alias", `{"alias": "exa mple.bit"}`, []string{"alias"}},
+		{"bad label", `{"map": {"-www": "192.0.2.1"}}`, []string{`map["-www"]`}},
+		{"bad shorthand", `{"map": {"www": "www.example.com"}}`, []string{`map["www"]`}},
+		{"nested", `{"map": {"www": {"ip": ["192.0.2.1", "x"], "map": {"a": {"tor": 1}}}}}`,
+			[]string{`map["www"].ip[1]`, `map["www"].map["a"].tor`}},
+		{"import namespace", `{"import": ["d/other", "id/other"]}`, []string{"import[1]"}},
+		{"import subdomain", `{"import": [["d/other", "bad host"]]}`, []string{"import[0][1]"}},
+		{"email", `{"email": "Host Master <hostmaster@example.com>"}`, []string{"email"}},
+		{"every error", `{"tor": 1, "email": "x", "ip": 1}`, []string{"email", "ip", "tor"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.value)
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got %v, want Errors", err)
+			}
+			paths := make([]string, len(errs))
+			for i, e := range errs {
+				paths[i] = e.Path
+			}
+			if !reflect.DeepEqual(paths, test.paths) {
+				t.Fatalf("got paths %q (%v), want %q", paths, err, test.paths)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", `{}`, `{}`},
+		{"single strings", `{"ip": ["192.0.2.1"], "ns": "ns.example.com"}`, `{"ip":"192.0.2.1","ns":"ns.example.com"}`},
+		{"map", `{"map": {"www": "192.0.2.2", "": {"ip6": "2001:db8::1"}}}`,
+			`{"map":{"":{"ip6":"2001:db8::1"},"www":{"ip":"192.0.2.2"}}}`},
+		{"import pair", `{"import": [["d/other", "www"]]}`, `{"import":[["d/other","www"]]}`},
+		{"import name", `{"import": ["d/other"]}`, `{"import":"d/other"}`},
+		{"extra fields", `{"zz": {"a": 1}, "email": "a@example.com", "aa": true}`,
+			`{"email":"a@example.com","aa":true,"zz":{"a":1}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := Parse(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %s, want %s", got, test.want)
+			}
+			again, err := Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, d) {
+				t.Fatalf("%s does not parse back to the same domain", got)
+			}
+		})
+	}
+}
+
+func TestValueErrors(t *testing.T) {
+	// {"info":"..."} adds 11 bytes to the info string.
+	info := strings.Repeat("x", nmcjson.MaxRPCValueLength-11)
+	fits := &Domain{Info: json.RawMessage(`"` + info + `"`)}
+	if value, err := fits.Value(); err != nil || len(value) != nmcjson.MaxRPCValueLength {
+		t.Fatalf("longest value: got %d bytes (%v), want %d", len(value), err, nmcjson.MaxRPCValueLength)
+	}
+	long := &Domain{Info: json.RawMessage(`"` + info + `x"`)}
+	if _, err := long.Value(); !errors.Is(err, nmcjson.ErrValueTooLong) {
+		t.Fatalf("long value: got %v, want %v", err, nmcjson.ErrValueTooLong)
+	}
+	invalid := &Domain{IP: []string{"2001:db8::1"}}
+	var errs Errors
+	if _, err := invalid.Value(); !errors.As(err, &errs) || errs[0].Path != "ip" {
+		t.Fatalf("invalid domain: got %v, want an error at ip", err)
+	}
+}
+
+func TestParseNameShow(t *testing.T) {
+	tests := []struct {
+		name   string
+		result nmcjson.NameShowResult
+		ip     string
+		err    bool
+	}{
+		{"domain", nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "d/example", Value: `{"ip":"192.0.2.1"}`}}, "192.0.2.1", false},
+		{"hex", nmcjson.NameShowResult{
+			NameValue: nmcjson.NameValue{
+				Name:         "642f6578616d706c65",
+				Value:        `{"ip":"192.0.2.1"}`,
+				NameEncoding: nmcjson.EncodingHex,
+			},
+		}, "192.0.2.1", false},
+		{"identity", nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "id/example", Value: `{}`}}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ParseNameShow(&test.result)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if err == nil && (len(d.IP) != 1 || d.IP[0] != test.ip) {
+				t.Fatalf("ip: got %q, want %q", d.IP, test.ip)
+			}
+		})
+	}
+}
@@ -0,0 +1,523 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// FieldError describes a malformed field of a domain value.  Path addresses
+// the field from the top of the value, such as map["www"].ip[1].
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+// Error returns the path and description of e.
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Errors lists every malformed field found in a domain value.
+type Errors []*FieldError
+
+// Error returns the descriptions of every error in e.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// i2pEncoding is the base64 alphabet of I2P destinations.
+var i2pEncoding = base64.NewEncoding(
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~")
+
+// parser collects the errors found while parsing a value.
+type parser struct {
+	errs Errors
+}
+
+// fail records a malformed field at path.
+func (p *parser) fail(path, format string, args ...interface{}) {
+	p.errs = append(p.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// fieldParser parses the field of a Domain stored under one key.
+type fieldParser func(p *parser, path string, raw json.RawMessage, d *Domain)
+
+// parsers holds the fieldParser of every key covered by Domain.
+var parsers map[string]fieldParser
+
+func init() {
+	parsers = map[string]fieldParser{
+		"ip": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.IP = p.strList(path, raw, func(s string) string {
+				if ip := net.ParseIP(s); ip == nil || ip.To4() == nil || strings.Contains(s, ":") {
+					return "invalid IPv4 address"
+				}
+				return ""
+			})
+		},
+		"ip6": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.IP6 = p.strList(path, raw, func(s string) string {
+				if ip := net.ParseIP(s); ip == nil || !strings.Contains(s, ":") {
+					return "invalid IPv6 address"
+				}
+				return ""
+			})
+		},
+		"ns": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.NS = p.strList(path, raw, func(s string) string {
+				if !validHostname(s) {
+					return "invalid host name"
+				}
+				return ""
+			})
+		},
+		"ds": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.DS = p.ds(path, raw)
+		},
+		"tls": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.TLS = p.tls(path, raw)
+		},
+		"tor": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			if s, ok := p.str(path, raw); ok {
+				if !validOnion(s) {
+					p.fail(path, "invalid onion address %q", s)
+				}
+				d.Tor = s
+			}
+		},
+		"i2p": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.I2P = p.i2p(path, raw)
+		},
+		"alias": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			if s, ok := p.str(path, raw); ok {
+				if !validTarget(s) {
+					p.fail(path, "invalid domain name %q", s)
+				}
+				d.Alias = s
+			}
+		},
+		"translate": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			if s, ok := p.str(path, raw); ok {
+				if !validTarget(s) {
+					p.fail(path, "invalid domain name %q", s)
+				}
+				d.Translate = s
+			}
+		},
+		"map": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.Map = p.subdomains(path, raw)
+		},
+		"import": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.Import = p.imports(path, raw)
+		},
+		"email": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			if s, ok := p.str(path, raw); ok {
+				if a, err := mail.ParseAddress(s); err != nil || a.Name != "" || a.Address != s {
+					p.fail(path, "invalid email address %q", s)
+				}
+				d.Email = s
+			}
+		},
+		"info": func(p *parser, path string, raw json.RawMessage, d *Domain) {
+			d.Info = compact(raw)
+		},
+	}
+}
+
+// Parse parses value, the value of a d/ name, into a Domain.  When any field
+// is malformed the returned error is an Errors listing all of them.
+func Parse(value string) (*Domain, error) {
+	return ParseBytes([]byte(value))
+}
+
+// ParseBytes is like Parse for a value given as raw bytes.
+func ParseBytes(value []byte) (*Domain, error) {
+	var p parser
+	d := p.domain("", value)
+	if len(p.errs) != 0 {
+		return nil, p.errs
+	}
+	return d, nil
+}
+
+// ParseNameShow parses the value of the d/ name described by r.
+func ParseNameShow(r *nmcjson.NameShowResult) (*Domain, error) {
+	name, err := r.NameBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(name, []byte(NamePrefix)) {
+		return nil, fmt.Errorf("%q is not a domain name", name)
+	}
+	value, err := r.ValueBytes()
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(value)
+}
+
+// domain parses the Domain Name Object raw found at path.
+func (p *parser) domain(path string, raw json.RawMessage) *Domain {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		p.fail(path, "not a JSON object")
+		return nil
+	}
+	d := new(Domain)
+	for _, key := range sortedKeys(fields) {
+		value := fields[key]
+		parse, ok := parsers[key]
+		if !ok {
+			if d.Extra == nil {
+				d.Extra = make(map[string]json.RawMessage)
+			}
+			d.Extra[key] = compact(value)
+			continue
+		}
+		parse(p, join(path, key), value, d)
+	}
+	return d
+}
+
+// str parses the JSON string raw found at path.
+func (p *parser) str(path string, raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		p.fail(path, "not a string")
+		return "", false
+	}
+	return s, true
+}
+
+// strList parses raw, a string or a list of strings found at path, and checks
+// each string with check, which returns a description of what is wrong with
+// it or "".
+func (p *parser) strList(path string, raw json.RawMessage, check func(string) string) []string {
+	var l []string
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if msg := check(s); msg != "" {
+			p.fail(path, "%s %q", msg, s)
+		}
+		return []string{s}
+	}
+	if err := json.Unmarshal(raw, &l); err != nil {
+		p.fail(path, "not a string or a list of strings")
+		return nil
+	}
+	for i, s := range l {
+		if msg := check(s); msg != "" {
+			p.fail(index(path, i), "%s %q", msg, s)
+		}
+	}
+	return l
+}
+
+// tuple parses raw, a list of four elements found at path, into three
+// integers checked against max and a base64 string.
+func (p *parser) tuple(path string, raw json.RawMessage, max [3]uint64) ([3]uint64, []byte, bool) {
+	var ints [3]uint64
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil || len(elems) != 4 {
+		p.fail(path, "not a list of four elements")
+		return ints, nil, false
+	}
+	ok := true
+	for i := range ints {
+		if err := json.Unmarshal(elems[i], &ints[i]); err != nil || ints[i] > max[i] {
+			p.fail(index(path, i), "not an integer from 0 to %d", max[i])
+			ok = false
+		}
+	}
+	var s string
+	if err := json.Unmarshal(elems[3], &s); err != nil {
+		p.fail(index(path, 3), "not a string")
+		return ints, nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		p.fail(index(path, 3), "invalid base64 data %q", s)
+		return ints, nil, false
+	}
+	return ints, data, ok
+}
+
+// list parses raw, a list found at path.
+func (p *parser) list(path string, raw json.RawMessage) []json.RawMessage {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		p.fail(path, "not a list")
+		return nil
+	}
+	return elems
+}
+
+// ds parses the ds field raw found at path.
+func (p *parser) ds(path string, raw json.RawMessage) []DS {
+	elems := p.list(path, raw)
+	records := make([]DS, 0, len(elems))
+	for i, elem := range elems {
+		ints, digest, ok := p.tuple(index(path, i), elem, [3]uint64{65535, 255, 255})
+		if !ok {
+			continue
+		}
+		records = append(records, DS{
+			KeyTag:     uint16(ints[0]),
+			Algorithm:  uint8(ints[1]),
+			DigestType: uint8(ints[2]),
+			Digest:     digest,
+		})
+	}
+	return records
+}
+
+// tls parses the tls field raw found at path.
+func (p *parser) tls(path string, raw json.RawMessage) []TLSA {
+	elems := p.list(path, raw)
+	records := make([]TLSA, 0, len(elems))
+	for i, elem := range elems {
+		elemPath := index(path, i)
+		ints, data, ok := p.tuple(elemPath, elem, [3]uint64{3, 1, 2})
+		if !ok {
+			continue
+		}
+		// Matching types 1 and 2 are SHA-256 and SHA-512 digests.
+		if want := map[uint64]int{1: 32, 2: 64}[ints[2]]; want != 0 && len(data) != want {
+			p.fail(index(elemPath, 3), "digest is %d bytes, expected %d", len(data), want)
+			continue
+		}
+		records = append(records, TLSA{
+			Usage:        uint8(ints[0]),
+			Selector:     uint8(ints[1]),
+			MatchingType: uint8(ints[2]),
+			Data:         data,
+		})
+	}
+	return records
+}
+
+// i2p parses the i2p field raw found at path.
+func (p *parser) i2p(path string, raw json.RawMessage) *I2P {
+	var i I2P
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&i); err != nil {
+		p.fail(path, "not an object of destination, name and b32 strings")
+		return nil
+	}
+	if i.Destination != "" {
+		if _, err := i2pEncoding.DecodeString(i.Destination); err != nil {
+			p.fail(join(path, "destination"), "invalid I2P destination")
+		}
+	}
+	if i.Name != "" && (!strings.HasSuffix(i.Name, ".i2p") || !validHostname(i.Name)) {
+		p.fail(join(path, "name"), "invalid I2P host name %q", i.Name)
+	}
+	if i.B32 != "" && !validB32(i.B32) {
+		p.fail(join(path, "b32"), "invalid I2P b32 address %q", i.B32)
+	}
+	return &i
+}
+
+// subdomains parses the map field raw found at path.  A subdomain given as a
+// string is shorthand for a Domain with that IPv4 or IPv6 address.
+func (p *parser) subdomains(path string, raw json.RawMessage) map[string]*Domain {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		p.fail(path, "not a JSON object")
+		return nil
+	}
+	m := make(map[string]*Domain, len(fields))
+	for _, l := range sortedKeys(fields) {
+		value := fields[l]
+		subPath := labelPath(path, l)
+		if l != "" && l != "*" && !validLabel(l) {
+			p.fail(subPath, "invalid label %q", l)
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			ip := net.ParseIP(s)
+			switch {
+			case ip == nil:
+				p.fail(subPath, "invalid IP address %q", s)
+			case strings.Contains(s, ":"):
+				m[l] = &Domain{IP6: []string{s}}
+			default:
+				m[l] = &Domain{IP: []string{s}}
+			}
+			continue
+		}
+		if d := p.domain(subPath, value); d != nil {
+			m[l] = d
+		}
+	}
+	return m
+}
+
+// imports parses the import field raw found at path: a name, or a list of
+// names and [name, subdomain] pairs.
+func (p *parser) imports(path string, raw json.RawMessage) []Import {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if !validImport(s) {
+			p.fail(path, "invalid import %q", s)
+		}
+		return []Import{{Name: s}}
+	}
+	elems := p.list(path, raw)
+	imports := make([]Import, 0, len(elems))
+	for i, elem := range elems {
+		elemPath := index(path, i)
+		var pair []string
+		if err := json.Unmarshal(elem, &s); err == nil {
+			pair = []string{s}
+		} else if err := json.Unmarshal(elem, &pair); err != nil || len(pair) < 1 || len(pair) > 2 {
+			p.fail(elemPath, "not a name or a [name, subdomain] pair")
+			continue
+		}
+		imp := Import{Name: pair[0]}
+		if len(pair) == 2 {
+			imp.Subdomain = pair[1]
+		}
+		if !validImport(imp.Name) {
+			p.fail(elemPath, "invalid import %q", imp.Name)
+			continue
+		}
+		if imp.Subdomain != "" && !validHostname(imp.Subdomain) {
+			p.fail(index(elemPath, 1), "invalid subdomain %q", imp.Subdomain)
+			continue
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// join returns the path of the field key of the object at path.
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// labelPath returns the path of the subdomain label in the map at path.
+func labelPath(path, label string) string {
+	return path + "[" + strconv.Quote(label) + "]"
+}
+
+// index returns the path of element i of the list at path.
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// sortedKeys returns the keys of fields in order, so errors are reported in
+// a stable order.
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compact returns raw without insignificant whitespace.
+func compact(raw json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
+
+// validLabel reports whether s is a valid DNS label.  Underscores are allowed
+// for service labels such as _tcp.
+func validLabel(s string) bool {
+	if len(s) == 0 || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validHostname reports whether s is a valid host name, optionally ending
+// with a dot.
+func validHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !validLabel(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// validTarget reports whether s is a valid alias or translate target: a host
+// name, or a name relative to the current domain ending with "@".
+func validTarget(s string) bool {
+	if rel := strings.TrimSuffix(s, "@"); rel != s {
+		rel = strings.TrimSuffix(rel, ".")
+		return rel == "" || validHostname(rel)
+	}
+	return validHostname(s)
+}
+
+// validImport reports whether s is the name of a d/ or dd/ name.
+func validImport(s string) bool {
+	return (strings.HasPrefix(s, NamePrefix) && len(s) > len(NamePrefix)) ||
+		(strings.HasPrefix(s, "dd/") && len(s) > len("dd/"))
+}
+
+// validBase32 reports whether s only holds lower case base32 characters.
+func validBase32(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z') && !(c >= '2' && c <= '7') {
+			return false
+		}
+	}
+	return true
+}
+
+// validOnion reports whether s is a version 2 or 3 onion address, possibly
+// with subdomains.
+func validOnion(s string) bool {
+	host := strings.TrimSuffix(s, ".onion")
+	if host == s || !validHostname(host) {
+		return false
+	}
+	labels := strings.Split(host, ".")
+	key := labels[len(labels)-1]
+	return (len(key) == 16 || len(key) == 56) && validBase32(key)
+}
+
+// validB32 reports whether s is an I2P b32 address.
+func validB32(s string) bool {
+	key := strings.TrimSuffix(s, ".b32.i2p")
+	return key != s && len(key) == 52 && validBase32(key)
+}
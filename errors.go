@@ -0,0 +1,123 @@
+package nmcjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+)
+
+// These errors classify the failures namecoind reports for name commands.
+// An *RPCError returned by a Client unwraps to one of them when its code and
+// message are recognised, so they can be tested with errors.Is.
+var (
+	// ErrNameNotFound indicates the name does not exist.
+	ErrNameNotFound = errors.New("name not found")
+
+	// ErrNameExpired indicates the name exists but has expired.
+	ErrNameExpired = errors.New("name expired")
+
+	// ErrNameAlreadyRegistered indicates a name_firstupdate or name_new
+	// for a name which is already active.
+	ErrNameAlreadyRegistered = errors.New("name already registered")
+
+	// ErrNamePending indicates an operation on a name which already has an
+	// unconfirmed operation in the mempool.  It says nothing about who
+	// sent the pending operation.
+	ErrNamePending = errors.New("name has pending operations")
+
+	// ErrNameNotUpdatable indicates a name_update of a name which is not
+	// active or not owned by the wallet.
+	ErrNameNotUpdatable = errors.New("name can not be updated")
+
+	// ErrFirstUpdateTooEarly indicates a name_firstupdate sent before its
+	// name_new is deep enough.
+	ErrFirstUpdateTooEarly = errors.New("name_firstupdate sent too early")
+
+	// ErrWalletLocked indicates the wallet must be unlocked first.
+	ErrWalletLocked = errors.New("wallet is locked")
+
+	// ErrInsufficientFunds indicates the wallet can not pay for the
+	// operation.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+
+	// ErrNameTooLong indicates the name exceeds the consensus limit.
+	ErrNameTooLong = errors.New("name is too long")
+
+	// ErrValueTooLong indicates the value exceeds the consensus limit, or
+	// the lower limit of the name RPCs.
+	ErrValueTooLong = errors.New("value is too long")
+)
+
+// errorMessages maps message fragments, in lower case, to the error they
+// indicate.  The fragments cover both legacy namecoind and Namecoin Core.
+var errorMessages = []struct {
+	fragment string
+	err      error
+}{
+	{"name not found", ErrNameNotFound},
+	{"failed to read from name db", ErrNameNotFound},
+	{"name expired", ErrNameExpired},
+	{"name has expired", ErrNameExpired},
+	{"this name is already active", ErrNameAlreadyRegistered},
+	{"there are pending operations on that name", ErrNamePending},
+	{"this name can not be updated", ErrNameNotUpdatable},
+	{"this name can't be updated", ErrNameNotUpdatable},
+	{"is not mature for first_update", ErrFirstUpdateTooEarly},
+	{"previous tx used for name_new is not", ErrFirstUpdateTooEarly},
+	{"wallet is locked", ErrWalletLocked},
+	{"walletpassphrase", ErrWalletLocked},
+	{"insufficient funds", ErrInsufficientFunds},
+	{"name is too long", ErrNameTooLong},
+	{"name too long", ErrNameTooLong},
+	{"value is too long", ErrValueTooLong},
+	{"value too long", ErrValueTooLong},
+}
+
+// RPCError is an error returned by namecoind in reply to a JSON-RPC request.
+type RPCError struct {
+	Code    btcjson.RPCErrorCode
+	Message string
+}
+
+// NewRPCError returns an RPCError with the given code and message.
+func NewRPCError(code btcjson.RPCErrorCode, message string) *RPCError {
+	return &RPCError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error returns the code and message of e.
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the classified error of e, such as ErrNameNotFound, or nil
+// if e is not recognised.
+func (e *RPCError) Unwrap() error {
+	switch e.Code {
+	case btcjson.ErrRPCWalletUnlockNeeded:
+		return ErrWalletLocked
+	case btcjson.ErrRPCWalletInsufficientFunds:
+		return ErrInsufficientFunds
+	}
+	msg := strings.ToLower(e.Message)
+	for _, m := range errorMessages {
+		if strings.Contains(msg, m.fragment) {
+			return m.err
+		}
+	}
+	return nil
+}
+
+// WrapRPCError converts a *btcjson.RPCError into an *RPCError so it can be
+// classified with errors.Is.  Other errors are returned unchanged.
+func WrapRPCError(err error) error {
+	var rpcErr *btcjson.RPCError
+	if errors.As(err, &rpcErr) {
+		return NewRPCError(rpcErr.Code, rpcErr.Message)
+	}
+	return err
+}
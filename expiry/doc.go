@@ -0,0 +1,16 @@
+/*
+Package expiry turns the block counts of name replies into expiry dates.
+
+A Calculator holds the chain tip, the time of the tip and the average time
+between blocks.  From the height of the last update of a name it computes
+the height at which the name expires, the blocks left until then and the
+estimated wall clock time of that block.  The block interval is either
+configured, defaulting to the ten minute target of Namecoin, or observed
+over recent blocks with Observe.
+
+NameList estimates every name of a name_list reply and sorts them by
+urgency, so the names expiring first, and those already expired, come
+first.  The estimates assume blocks keep arriving at the average interval,
+so dates far in the future are approximate.
+*/
+package expiry
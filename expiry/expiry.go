@@ -0,0 +1,208 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+)
+
+// DefaultBlockInterval is the target time between Namecoin blocks, used
+// when Calculator.BlockInterval is zero.
+const DefaultBlockInterval = 10 * time.Minute
+
+// Node is the part of nmcjson.Client used by Observe.
+type Node interface {
+	GetBlockCount(ctx context.Context) (int64, error)
+	GetBlockHash(ctx context.Context, height int64) (string, error)
+	GetBlockHeaderVerbose(ctx context.Context, hash string) (*btcjson.GetBlockHeaderVerboseResult, error)
+}
+
+// Enforce that nmcjson.Client satisfies the Node interface.
+var _ Node = (*nmcjson.Client)(nil)
+
+// Calculator estimates when names expire from the state of the chain.
+type Calculator struct {
+	// Params are the rules of the network.  Nil means
+	// nmcjson.MainNetParams.
+	Params *nmcjson.Params
+
+	// TipHeight is the height of the current tip.
+	TipHeight int64
+
+	// TipTime is the time of the tip.  The zero time means the current
+	// time, read once per call, so the estimates of one NameList are
+	// consistent.
+	TipTime time.Time
+
+	// BlockInterval is the average time between blocks.  Zero means
+	// DefaultBlockInterval.
+	BlockInterval time.Duration
+}
+
+// Estimate is when a name expires.
+type Estimate struct {
+	// Height is the height of the last update of the name.
+	Height int64
+
+	// ExpiryHeight is the first height at which the name is expired.
+	ExpiryHeight int64
+
+	// BlocksLeft is the number of blocks from the tip to ExpiryHeight.
+	// It is zero or negative once the name has expired.
+	BlocksLeft int64
+
+	// Time is the estimated time of the block at ExpiryHeight, which is
+	// in the past once the name has expired.
+	Time time.Time
+}
+
+// Expired reports whether the name has expired at the tip.
+func (e *Estimate) Expired() bool {
+	return e.BlocksLeft <= 0
+}
+
+// Remaining returns the estimated time left before the name expires, which
+// is negative once it has expired.
+func (e *Estimate) Remaining(now time.Time) time.Duration {
+	return e.Time.Sub(now)
+}
+
+// Observe returns a Calculator for params at the tip of node, with the block
+// interval averaged over the last window blocks.  If the chain is shorter than
+// two blocks, or its timestamps do not increase, DefaultBlockInterval is used.
+func Observe(ctx context.Context, node Node, params *nmcjson.Params, window int64) (*Calculator, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("expiry: window must be positive, got %d", window)
+	}
+	tip, err := node.GetBlockCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from := tip - window
+	if from < 0 {
+		from = 0
+	}
+	tipTime, err := blockTime(ctx, node, tip)
+	if err != nil {
+		return nil, err
+	}
+	c := &Calculator{Params: params, TipHeight: tip, TipTime: tipTime}
+	if from < tip {
+		fromTime, err := blockTime(ctx, node, from)
+		if err != nil {
+			return nil, err
+		}
+		if elapsed := tipTime.Sub(fromTime); elapsed > 0 {
+			c.BlockInterval = elapsed / time.Duration(tip-from)
+		}
+	}
+	return c, nil
+}
+
+// blockTime returns the timestamp of the block at height.
+func blockTime(ctx context.Context, node Node, height int64) (time.Time, error) {
+	hash, err := node.GetBlockHash(ctx, height)
+	if err != nil {
+		return time.Time{}, err
+	}
+	header, err := node.GetBlockHeaderVerbose(ctx, hash)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(header.Time, 0), nil
+}
+
+// params returns the params of c.
+func (c *Calculator) params() *nmcjson.Params {
+	if c.Params == nil {
+		return &nmcjson.MainNetParams
+	}
+	return c.Params
+}
+
+// interval returns the block interval of c.
+func (c *Calculator) interval() time.Duration {
+	if c.BlockInterval <= 0 {
+		return DefaultBlockInterval
+	}
+	return c.BlockInterval
+}
+
+// tipTime returns the time of the tip, or the current time if TipTime is
+// zero.
+func (c *Calculator) tipTime() time.Time {
+	if c.TipTime.IsZero() {
+		return time.Now()
+	}
+	return c.TipTime
+}
+
+// TimeAt returns the estimated time of the block at height, which may be
+// before the tip.
+func (c *Calculator) TimeAt(height int64) time.Time {
+	return c.timeAt(height, c.tipTime())
+}
+
+// timeAt is TimeAt with the time of the tip read by the caller.
+func (c *Calculator) timeAt(height int64, tipTime time.Time) time.Time {
+	return tipTime.Add(time.Duration(height-c.TipHeight) * c.interval())
+}
+
+// Estimate returns when a name last updated at nameHeight expires.
+func (c *Calculator) Estimate(nameHeight int64) Estimate {
+	return c.estimate(nameHeight, c.tipTime())
+}
+
+// estimate is Estimate with the time of the tip read by the caller.
+func (c *Calculator) estimate(nameHeight int64, tipTime time.Time) Estimate {
+	expiryHeight := c.params().ExpiryHeight(nameHeight)
+	return Estimate{
+		Height:       nameHeight,
+		ExpiryHeight: expiryHeight,
+		BlocksLeft:   expiryHeight - c.TipHeight,
+		Time:         c.timeAt(expiryHeight, tipTime),
+	}
+}
+
+// NameShow returns when the name of a name_show reply expires.
+func (c *Calculator) NameShow(r *nmcjson.NameShowResult) Estimate {
+	return c.Estimate(r.Height)
+}
+
+// NameEstimate is a name of a name_list reply and when it expires.
+type NameEstimate struct {
+	nmcjson.NameListResult
+	Estimate Estimate
+}
+
+// NameList returns when each name of a name_list reply expires, sorted by
+// urgency.
+func (c *Calculator) NameList(results []nmcjson.NameListResult) []NameEstimate {
+	tipTime := c.tipTime()
+	estimates := make([]NameEstimate, 0, len(results))
+	for _, r := range results {
+		estimates = append(estimates, NameEstimate{
+			NameListResult: r,
+			Estimate:       c.estimate(r.Height, tipTime),
+		})
+	}
+	SortByUrgency(estimates)
+	return estimates
+}
+
+// SortByUrgency sorts estimates by expiry height, so that the names
+// expiring first come first.  Names expiring at the same height are sorted
+// by name.
+func SortByUrgency(estimates []NameEstimate) {
+	sort.SliceStable(estimates, func(i, j int) bool {
+		a, b := &estimates[i], &estimates[j]
+		if a.Estimate.ExpiryHeight != b.Estimate.ExpiryHeight {
+			return a.Estimate.ExpiryHeight < b.Estimate.ExpiryHeight
+		}
+		return a.Name < b.Name
+	})
+}
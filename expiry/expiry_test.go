@@ -0,0 +1,167 @@
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name         string
+		nameHeight   int64
+		expiryHeight int64
+	}{
+		{"genesis", 0, 12000},
+		{"last before the increase", 11999, 23999},
+		{"expiring at 24000", 12000, 24000},
+		{"first after the increase", 12001, 48001},
+		{"during the increase", 30000, 66000},
+		{"at 48000", 48000, 84000},
+		{"after the increase", 500000, 536000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Calculator{Params: &nmcjson.MainNetParams}
+			e := c.Estimate(test.nameHeight)
+			if e.ExpiryHeight != test.expiryHeight {
+				t.Fatalf("expiry height: got %d, want %d", e.ExpiryHeight, test.expiryHeight)
+			}
+			// The sign of BlocksLeft agrees with the expires_in of
+			// name_show around the expiry.
+			for _, tip := range []int64{test.expiryHeight - 1, test.expiryHeight, test.expiryHeight + 1} {
+				c.TipHeight = tip
+				e := c.Estimate(test.nameHeight)
+				if e.BlocksLeft != test.expiryHeight-tip {
+					t.Fatalf("at %d: got %d blocks left, want %d", tip, e.BlocksLeft, test.expiryHeight-tip)
+				}
+				if want := nmcjson.MainNetParams.IsExpired(test.nameHeight, tip); e.Expired() != want {
+					t.Fatalf("at %d: got expired %v, want %v", tip, e.Expired(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeAt(t *testing.T) {
+	tipTime := time.Unix(1600000000, 0)
+	c := &Calculator{TipHeight: 100, TipTime: tipTime}
+	if got, want := c.TimeAt(106), tipTime.Add(time.Hour); !got.Equal(want) {
+		t.Fatalf("after the tip: got %v, want %v", got, want)
+	}
+	c.BlockInterval = time.Minute
+	if got, want := c.TimeAt(40), tipTime.Add(-time.Hour); !got.Equal(want) {
+		t.Fatalf("before the tip: got %v, want %v", got, want)
+	}
+
+	// Without a tip time, the current time is read once per call.
+	c = &Calculator{TipHeight: 100}
+	before := time.Now()
+	got := c.TimeAt(106)
+	after := time.Now()
+	if got.Before(before.Add(time.Hour)) || got.After(after.Add(time.Hour)) {
+		t.Fatalf("from now: got %v, want between %v and %v", got, before.Add(time.Hour), after.Add(time.Hour))
+	}
+	results := make([]nmcjson.NameListResult, 100)
+	for i := range results {
+		results[i] = nmcjson.NameListResult{NameValue: nmcjson.NameValue{Name: fmt.Sprintf("d/%03d", i)}, Height: 50}
+	}
+	estimates := c.NameList(results)
+	for _, e := range estimates {
+		if !e.Estimate.Time.Equal(estimates[0].Estimate.Time) {
+			t.Fatalf("%s: got %v, want %v like %s", e.Name, e.Estimate.Time, estimates[0].Estimate.Time, estimates[0].Name)
+		}
+	}
+}
+
+// chainNode is a Node of a chain with the given block timestamps.
+type chainNode struct {
+	times []int64
+}
+
+func (n *chainNode) GetBlockCount(ctx context.Context) (int64, error) {
+	return int64(len(n.times) - 1), nil
+}
+
+func (n *chainNode) GetBlockHash(ctx context.Context, height int64) (string, error) {
+	if height < 0 || height >= int64(len(n.times)) {
+		return "", fmt.Errorf("height %d out of range", height)
+	}
+	return strconv.FormatInt(height, 10), nil
+}
+
+func (n *chainNode) GetBlockHeaderVerbose(ctx context.Context, hash string) (*btcjson.GetBlockHeaderVerboseResult, error) {
+	height, err := strconv.ParseInt(hash, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &btcjson.GetBlockHeaderVerboseResult{Height: int32(height), Time: n.times[height]}, nil
+}
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		times    []int64
+		window   int64
+		interval time.Duration // zero when the default is used
+	}{
+		{"window", []int64{0, 600, 1200, 1260, 1320, 1380}, 3, time.Minute},
+		{"window beyond genesis", []int64{0, 60, 120, 180}, 100, time.Minute},
+		{"window ending at genesis", []int64{0, 60, 120, 180}, 3, time.Minute},
+		{"only genesis", []int64{1000}, 10, 0},
+		{"equal timestamps", []int64{1000, 1000, 1000}, 2, 0},
+		{"decreasing timestamps", []int64{1000, 900, 800}, 2, 0},
+		{"timestamps going back within the window", []int64{0, 600, 300, 900}, 3, 5 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &chainNode{times: test.times}
+			c, err := Observe(context.Background(), node, &nmcjson.RegTestParams, test.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tip := int64(len(test.times) - 1)
+			if c.TipHeight != tip || !c.TipTime.Equal(time.Unix(test.times[tip], 0)) {
+				t.Fatalf("tip: got %d at %v, want %d at %v", c.TipHeight, c.TipTime, tip, time.Unix(test.times[tip], 0))
+			}
+			if c.BlockInterval != test.interval {
+				t.Fatalf("interval: got %v, want %v", c.BlockInterval, test.interval)
+			}
+			if c.Params != &nmcjson.RegTestParams {
+				t.Fatal("params: not the ones given")
+			}
+		})
+	}
+
+	if _, err := Observe(context.Background(), &chainNode{times: []int64{0}}, nil, 0); err == nil {
+		t.Fatal("zero window: got no error")
+	}
+}
+
+func TestSortByUrgency(t *testing.T) {
+	c := &Calculator{TipHeight: 40000}
+	estimates := c.NameList([]nmcjson.NameListResult{
+		{NameValue: nmcjson.NameValue{Name: "d/late"}, Height: 39000},
+		{NameValue: nmcjson.NameValue{Name: "d/b"}, Height: 30000},
+		{NameValue: nmcjson.NameValue{Name: "d/expired"}, Height: 100},
+		{NameValue: nmcjson.NameValue{Name: "d/a"}, Height: 30000},
+		{NameValue: nmcjson.NameValue{Name: "d/c"}, Height: 30000},
+	})
+	want := []string{"d/expired", "d/a", "d/b", "d/c", "d/late"}
+	if len(estimates) != len(want) {
+		t.Fatalf("got %d estimates, want %d", len(estimates), len(want))
+	}
+	for i, e := range estimates {
+		if e.Name != want[i] {
+			t.Fatalf("position %d: got %s, want %s", i, e.Name, want[i])
+		}
+	}
+	if !estimates[0].Estimate.Expired() || estimates[1].Estimate.Expired() {
+		t.Fatal("only d/expired should have expired")
+	}
+}
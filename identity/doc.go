@@ -0,0 +1,16 @@
+/*
+Package identity parses and verifies the values of Namecoin id/ names.
+
+The value of an id/ name is a JSON object describing a person or
+organisation: a display name, contact addresses (email, xmpp, bitmessage),
+GPG key fingerprints, a Namecoin address for payments, and the signer
+addresses allowed to sign messages on behalf of the identity.  Parse
+decodes a value into an Identity and checks the format of every field,
+returning an Errors list whose entries are addressed by field, such as
+gpg[1].fpr.
+
+Identity.Verify checks a message signature, in the format produced by the
+signmessage RPC of namecoind, against the declared signer addresses, so a
+login system can authenticate the owner of an id/ name offline.
+*/
+package identity
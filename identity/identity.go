@@ -0,0 +1,295 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+)
+
+// NamePrefix is the namespace prefix of identity names.
+const NamePrefix = "id/"
+
+// Identity models the value of an id/ name.
+type Identity struct {
+	// Name is the display name of the identity.
+	Name string
+
+	// Email is the email address of the identity.
+	Email string
+
+	// GPG lists the identity's OpenPGP keys.
+	GPG []GPGKey
+
+	// Bitmessage is the identity's Bitmessage address, starting with
+	// "BM-".
+	Bitmessage string
+
+	// XMPP is the identity's Jabber ID.
+	XMPP string
+
+	// Signer lists the Namecoin addresses allowed to sign messages on
+	// behalf of the identity.
+	Signer []string
+
+	// Namecoin is the Namecoin address which receives payments.
+	Namecoin string
+
+	// Extra holds the fields not covered by the model.
+	Extra map[string]json.RawMessage
+}
+
+// GPGKey is an OpenPGP key of an identity, given either as a fingerprint or
+// as an object such as {"v": "pka1", "fpr": "...", "uri": "..."}.
+type GPGKey struct {
+	// Version is the version of the key reference, such as "pka1".
+	Version string `json:"v,omitempty"`
+
+	// Fingerprint is the hex encoded fingerprint of the key.  Spaces are
+	// allowed between groups of digits.
+	Fingerprint string `json:"fpr"`
+
+	// URI is where the key can be fetched from.
+	URI string `json:"uri,omitempty"`
+}
+
+// FieldError describes a malformed field of an identity value.  Path
+// addresses the field from the top of the value, such as gpg[1].fpr.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+// Error returns the path and description of e.
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Errors lists every malformed field found in an identity value.
+type Errors []*FieldError
+
+// Error returns the descriptions of every error in e.
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// parser collects the errors found while parsing a value.
+type parser struct {
+	params *nmcjson.Params
+	errs   Errors
+}
+
+// fail records a malformed field at path.
+func (p *parser) fail(path, format string, args ...interface{}) {
+	p.errs = append(p.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Parse parses value, the value of an id/ name, into an Identity.  When any
+// field is malformed the returned error is an Errors listing all of them.
+// Addresses are checked against the network of params.
+func Parse(value string, params *nmcjson.Params) (*Identity, error) {
+	return ParseBytes([]byte(value), params)
+}
+
+// ParseBytes is like Parse for a value given as raw bytes.
+func ParseBytes(value []byte, params *nmcjson.Params) (*Identity, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil || fields == nil {
+		return nil, Errors{{Message: "not a JSON object"}}
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := parser{params: params}
+	id := new(Identity)
+	for _, key := range keys {
+		raw := fields[key]
+		switch key {
+		case "name":
+			id.Name, _ = p.str(key, raw)
+		case "email":
+			if s, ok := p.str(key, raw); ok {
+				if a, err := mail.ParseAddress(s); err != nil || a.Name != "" || a.Address != s {
+					p.fail(key, "invalid email address %q", s)
+				}
+				id.Email = s
+			}
+		case "gpg":
+			id.GPG = p.gpg(key, raw)
+		case "bitmessage":
+			if s, ok := p.str(key, raw); ok {
+				if !validBitmessage(s) {
+					p.fail(key, "invalid Bitmessage address %q", s)
+				}
+				id.Bitmessage = s
+			}
+		case "xmpp":
+			if s, ok := p.str(key, raw); ok {
+				if !validJID(s) {
+					p.fail(key, "invalid Jabber ID %q", s)
+				}
+				id.XMPP = s
+			}
+		case "signer":
+			id.Signer = p.addresses(key, raw)
+		case "namecoin":
+			if s, ok := p.str(key, raw); ok {
+				if _, err := address.Decode(s, p.params); err != nil {
+					p.fail(key, "%v", err)
+				}
+				id.Namecoin = s
+			}
+		default:
+			if id.Extra == nil {
+				id.Extra = make(map[string]json.RawMessage)
+			}
+			id.Extra[key] = raw
+		}
+	}
+	if len(p.errs) != 0 {
+		return nil, p.errs
+	}
+	return id, nil
+}
+
+// ParseNameShow parses the value of the id/ name described by r, a name_show
+// result of a node of the network of params.
+func ParseNameShow(r *nmcjson.NameShowResult, params *nmcjson.Params) (*Identity, error) {
+	name, err := r.NameBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(name, []byte(NamePrefix)) {
+		return nil, fmt.Errorf("%q is not an identity name", name)
+	}
+	value, err := r.ValueBytes()
+	if err != nil {
+		return nil, err
+	}
+	return ParseBytes(value, params)
+}
+
+// str parses the JSON string raw found at path.
+func (p *parser) str(path string, raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		p.fail(path, "not a string")
+		return "", false
+	}
+	return s, true
+}
+
+// gpg parses the gpg field raw found at path: a key or a list of keys, each
+// given as a fingerprint or an object.
+func (p *parser) gpg(path string, raw json.RawMessage) []GPGKey {
+	elems := []json.RawMessage{raw}
+	single := true
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			p.fail(path, "not a list")
+			return nil
+		}
+		single = false
+	}
+	keys := make([]GPGKey, 0, len(elems))
+	for i, elem := range elems {
+		elemPath := path
+		if !single {
+			elemPath = path + "[" + strconv.Itoa(i) + "]"
+		}
+		var key GPGKey
+		if err := json.Unmarshal(elem, &key.Fingerprint); err != nil {
+			key = GPGKey{}
+			if err := json.Unmarshal(elem, &key); err != nil {
+				p.fail(elemPath, "not a fingerprint or a key object")
+				continue
+			}
+			elemPath += ".fpr"
+		}
+		if !validFingerprint(key.Fingerprint) {
+			p.fail(elemPath, "invalid fingerprint %q", key.Fingerprint)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// addresses parses raw, a Namecoin address or a list of them found at path.
+func (p *parser) addresses(path string, raw json.RawMessage) []string {
+	var l []string
+	var s string
+	single := json.Unmarshal(raw, &s) == nil
+	if single {
+		l = []string{s}
+	} else if err := json.Unmarshal(raw, &l); err != nil {
+		p.fail(path, "not an address or a list of addresses")
+		return nil
+	}
+	for i, addr := range l {
+		if _, err := address.Decode(addr, p.params); err != nil {
+			elemPath := path
+			if !single {
+				elemPath = path + "[" + strconv.Itoa(i) + "]"
+			}
+			p.fail(elemPath, "%v", err)
+		}
+	}
+	return l
+}
+
+// validFingerprint reports whether s is a version 4 or 5 OpenPGP
+// fingerprint, in hex with optional spaces.
+func validFingerprint(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// validBitmessage reports whether s is a Bitmessage address with a valid
+// checksum.
+func validBitmessage(s string) bool {
+	rest := strings.TrimPrefix(s, "BM-")
+	if rest == s {
+		return false
+	}
+	b := base58.Decode(rest)
+	if len(b) < 5 {
+		return false
+	}
+	data, checksum := b[:len(b)-4], b[len(b)-4:]
+	first := sha512.Sum512(data)
+	second := sha512.Sum512(first[:])
+	return bytes.Equal(second[:4], checksum)
+}
+
+// validJID reports whether s is a bare Jabber ID of the form local@domain.
+func validJID(s string) bool {
+	at := strings.IndexByte(s, '@')
+	if at <= 0 || at == len(s)-1 || strings.ContainsAny(s, " /") {
+		return false
+	}
+	return !strings.Contains(s[at+1:], "@")
+}
@@ -0,0 +1,104 @@
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// Addresses of the 20 byte hash 000102...13.
+const (
+	mainP2PKH  = "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu"
+	mainP2WPKH = "nc1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnjqsk4h"
+	testP2PKH  = "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth"
+)
+
+func TestParse(t *testing.T) {
+	value := `{
+		"name": "Example",
+		"email": "me@example.com",
+		"gpg": ["0123456789ABCDEF0123456789ABCDEF01234567",
+			{"v": "pka1", "fpr": "0123 4567 89AB CDEF 0123 4567 89AB CDEF 0123 4567", "uri": "https://example.com/key.asc"}],
+		"bitmessage": "BM-87SXbLGwg9NsgyibKMKFDzJ8kDRjfxeWZZN",
+		"xmpp": "me@example.com",
+		"signer": "` + mainP2PKH + `",
+		"namecoin": "` + mainP2WPKH + `",
+		"website": "https://example.com"
+	}`
+	want := &Identity{
+		Name:  "Example",
+		Email: "me@example.com",
+		GPG: []GPGKey{
+			{Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567"},
+			{Version: "pka1", Fingerprint: "0123 4567 89AB CDEF 0123 4567 89AB CDEF 0123 4567", URI: "https://example.com/key.asc"},
+		},
+		Bitmessage: "BM-87SXbLGwg9NsgyibKMKFDzJ8kDRjfxeWZZN",
+		XMPP:       "me@example.com",
+		Signer:     []string{mainP2PKH},
+		Namecoin:   mainP2WPKH,
+		Extra:      map[string]json.RawMessage{"website": json.RawMessage(`"https://example.com"`)},
+	}
+	got, err := Parse(value, &nmcjson.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		params *nmcjson.Params
+		paths  []string
+	}{
+		{"not an object", `"me@example.com"`, &nmcjson.MainNetParams, []string{""}},
+		{"name", `{"name": 1}`, &nmcjson.MainNetParams, []string{"name"}},
+		{"email", `{"email": "Me <me@example.com>"}`, &nmcjson.MainNetParams, []string{"email"}},
+		{"gpg fingerprint", `{"gpg": "0123"}`, &nmcjson.MainNetParams, []string{"gpg"}},
+		{"gpg list", `{"gpg": ["0123456789ABCDEF0123456789ABCDEF01234567", "xyz"]}`, &nmcjson.MainNetParams, []string{"gpg[1]"}},
+		{"gpg object", `{"gpg": [{"fpr": "0123"}]}`, &nmcjson.MainNetParams, []string{"gpg[0].fpr"}},
+		{"bitmessage checksum", `{"bitmessage": "BM-87SXbLGwg9NsgyibKMKFDzJ8kDRjfvVaz7R"}`, &nmcjson.MainNetParams, []string{"bitmessage"}},
+		{"bitmessage prefix", `{"bitmessage": "87SXbLGwg9NsgyibKMKFDzJ8kDRjfxeWZZN"}`, &nmcjson.MainNetParams, []string{"bitmessage"}},
+		{"xmpp", `{"xmpp": "me@example.com/home"}`, &nmcjson.MainNetParams, []string{"xmpp"}},
+		{"signer network", `{"signer": "` + testP2PKH + `"}`, &nmcjson.MainNetParams, []string{"signer"}},
+		{"signer list", `{"signer": ["` + mainP2PKH + `", "garbage"]}`, &nmcjson.MainNetParams, []string{"signer[1]"}},
+		{"namecoin network", `{"namecoin": "` + mainP2WPKH + `"}`, &nmcjson.TestNet3Params, []string{"namecoin"}},
+		{"every error", `{"xmpp": "x", "name": 1, "email": "x"}`, &nmcjson.MainNetParams, []string{"email", "name", "xmpp"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.value, test.params)
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got %v, want Errors", err)
+			}
+			paths := make([]string, len(errs))
+			for i, e := range errs {
+				paths[i] = e.Path
+			}
+			if !reflect.DeepEqual(paths, test.paths) {
+				t.Fatalf("got paths %q (%v), want %q", paths, err, test.paths)
+			}
+		})
+	}
+}
+
+func TestParseNameShow(t *testing.T) {
+	value := `{"namecoin": "` + testP2PKH + `"}`
+	id, err := ParseNameShow(&nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "id/example", Value: value}}, &nmcjson.RegTestParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Namecoin != testP2PKH {
+		t.Fatalf("namecoin: got %q, want %q", id.Namecoin, testP2PKH)
+	}
+	if _, err := ParseNameShow(&nmcjson.NameShowResult{NameValue: nmcjson.NameValue{Name: "d/example", Value: value}}, &nmcjson.RegTestParams); err == nil {
+		t.Fatal("d/example: got no error")
+	}
+}
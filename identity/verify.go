@@ -0,0 +1,69 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+)
+
+// messageMagic is prepended to messages before they are hashed by the
+// signmessage and verifymessage RPCs of namecoind.
+const messageMagic = "Namecoin Signed Message:\n"
+
+// VerifyMessage reports whether signature, as returned by the signmessage RPC
+// of namecoind, is a signature of message by the key of addr, an address of
+// the network of params.  Only P2PKH addresses can sign messages; other
+// addresses return an error.
+func VerifyMessage(addr, message, signature string, params *nmcjson.Params) (bool, error) {
+	decoded, err := address.Decode(addr, params)
+	if err != nil {
+		return false, err
+	}
+	keyHash, ok := decoded.(*address.PubKeyHash)
+	if !ok {
+		return false, fmt.Errorf("address %s can not sign messages", addr)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("malformed signature: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := wire.WriteVarString(&buf, 0, messageMagic); err != nil {
+		return false, err
+	}
+	if err := wire.WriteVarString(&buf, 0, message); err != nil {
+		return false, err
+	}
+	pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), sig,
+		chainhash.DoubleHashB(buf.Bytes()))
+	if err != nil {
+		return false, nil
+	}
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+
+	return bytes.Equal(btcutil.Hash160(serialized), keyHash.Hash()), nil
+}
+
+// Verify reports whether signature, as returned by the signmessage RPC of
+// namecoind, is a signature of message by one of the signer addresses of
+// id on the network of params, and returns that address.  Signer addresses
+// which can not sign messages are skipped.
+func (id *Identity) Verify(message, signature string, params *nmcjson.Params) (string, bool) {
+	for _, signer := range id.Signer {
+		if ok, err := VerifyMessage(signer, message, signature, params); err == nil && ok {
+			return signer, true
+		}
+	}
+	return "", false
+}
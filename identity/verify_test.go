@@ -0,0 +1,124 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+)
+
+// signMessage signs message with key as the signmessage RPC of namecoind.
+func signMessage(t *testing.T, key *btcec.PrivateKey, message string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, messageMagic)
+	wire.WriteVarString(&buf, 0, message)
+	sig, err := btcec.SignCompact(btcec.S256(), key, chainhash.DoubleHashB(buf.Bytes()), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestVerifyMessage(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyHash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+	addr := func(a address.Address, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.String()
+	}
+	mainnet, regtest := &nmcjson.MainNetParams, &nmcjson.RegTestParams
+	signature := signMessage(t, key, "hello")
+
+	tests := []struct {
+		name      string
+		addr      string
+		signature string
+		message   string
+		ok        bool
+		err       bool
+	}{{
+		name:      "signed",
+		addr:      addr(address.NewPubKeyHash(keyHash, mainnet)),
+		signature: signature,
+		message:   "hello",
+		ok:        true,
+	}, {
+		name:      "other message",
+		addr:      addr(address.NewPubKeyHash(keyHash, mainnet)),
+		signature: signature,
+		message:   "goodbye",
+	}, {
+		name:      "other key",
+		addr:      addr(address.NewPubKeyHash(make([]byte, 20), mainnet)),
+		signature: signature,
+		message:   "hello",
+	}, {
+		name:      "P2SH with the key hash",
+		addr:      addr(address.NewScriptHash(keyHash, mainnet)),
+		signature: signature,
+		message:   "hello",
+		err:       true,
+	}, {
+		name:      "segwit with the key hash",
+		addr:      addr(address.NewWitness(keyHash, mainnet)),
+		signature: signature,
+		message:   "hello",
+		err:       true,
+	}, {
+		name:      "other network",
+		addr:      addr(address.NewPubKeyHash(keyHash, regtest)),
+		signature: signature,
+		message:   "hello",
+		err:       true,
+	}, {
+		name:      "malformed signature",
+		addr:      addr(address.NewPubKeyHash(keyHash, mainnet)),
+		signature: "not base64!",
+		message:   "hello",
+		err:       true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := VerifyMessage(test.addr, test.message, test.signature, mainnet)
+			if (err != nil) != test.err {
+				t.Fatalf("error: got %v, want error %v", err, test.err)
+			}
+			if ok != test.ok {
+				t.Fatalf("got %v, want %v", ok, test.ok)
+			}
+		})
+	}
+}
+
+func TestIdentityVerify(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := address.NewPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), &nmcjson.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := address.NewScriptHash(make([]byte, 20), &nmcjson.MainNetParams)
+	id := &Identity{Signer: []string{other.String(), signer.String()}}
+
+	got, ok := id.Verify("login", signMessage(t, key, "login"), &nmcjson.MainNetParams)
+	if !ok || got != signer.String() {
+		t.Fatalf("Verify: got %q %v, want %q true", got, ok, signer)
+	}
+	if _, ok := id.Verify("login", signMessage(t, key, "other"), &nmcjson.MainNetParams); ok {
+		t.Fatal("Verify accepted the signature of another message")
+	}
+}
@@ -0,0 +1,33 @@
+/*
+Package index keeps a local copy of the name database of a node, so that
+name_show and name_history lookups are served without a round trip to
+namecoind.
+
+An Index reads the chain from a Node, which an nmcjson.Client satisfies.
+An Index is kept in a Store, an embedded key-value store.  FileStore persists
+it to a single append-only log file and MemStore keeps it in memory; other
+stores, such as bolt or leveldb, can be used by implementing the Store
+interface.
+
+	store, err := index.OpenFileStore("names.db")
+	...
+	ix, err := index.New(client, store, nil)
+	...
+	go ix.Follow(ctx, 30*time.Second)
+	...
+	name, err := ix.NameShow(ctx, "d/example")
+
+The first Sync loads every name with name_scan.  Later ones read the new
+blocks with getblock and apply the name operations of their outputs.  Blocks
+which are reorganised away are disconnected with the undo data kept for the
+last Options.MaxReorgDepth blocks.  A deeper reorganisation, or one which
+replaces the block the initial load started at, rebuilds the index.
+The sync height is written with every block, so a restarted Index continues
+where it stopped, and an interrupted initial load resumes after the last page
+it stored.
+
+Lookups take raw names and return raw names and values, as with empty
+encodings.  Expiry and the addresses of name outputs follow Options.Params.
+The history of a name starts with the value found by the initial load.
+*/
+package index
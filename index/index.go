@@ -0,0 +1,647 @@
+package index
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+const (
+	// DefaultMaxReorgDepth is the number of blocks which can be
+	// disconnected by a reorganisation when Options.MaxReorgDepth is zero.
+	DefaultMaxReorgDepth = 100
+)
+
+// Keys of the store.  Names are stored raw after prefixName, and heights as
+// fixed width hex after prefixUndo so they sort in order.
+const (
+	keyTip     = "tip"
+	keyLoad    = "load"
+	prefixName = "n/"
+	prefixUndo = "u/"
+)
+
+var (
+	// ErrNotLoaded indicates a lookup before the initial load completed.
+	ErrNotLoaded = errors.New("index has not been loaded")
+
+	// ErrReorgTooDeep indicates a reorganisation which disconnects blocks
+	// beyond the undo data kept by the index.  Sync rebuilds the index
+	// when it happens.
+	ErrReorgTooDeep = errors.New("reorganisation is deeper than the undo data")
+)
+
+// Options configures an Index.
+type Options struct {
+	// MaxReorgDepth is the number of blocks for which undo data is kept.
+	// Zero means DefaultMaxReorgDepth.
+	MaxReorgDepth int64
+
+	// PageSize is the number of names requested per name_scan during
+	// the initial load.  Zero means nmcjson.DefaultScanPageSize.
+	PageSize int
+
+	// Params are the params of the network of the node, which set the
+	// expiry of names and the encoding of their addresses.  Nil means
+	// nmcjson.MainNetParams.
+	Params *nmcjson.Params
+}
+
+// tip identifies the last block applied to the index.
+type tip struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// loadState is the progress of an interrupted initial load: the block it is
+// anchored at and the hex encoded name of the last stored page.
+type loadState struct {
+	Tip    tip    `json:"tip"`
+	Cursor string `json:"cursor"`
+}
+
+// entry is one value of a name.
+type entry struct {
+	Value   []byte `json:"value"`
+	Txid    string `json:"txid"`
+	Vout    int    `json:"vout"`
+	Address string `json:"address"`
+	Height  int64  `json:"height"`
+}
+
+// undo records the names which were given a new entry by a block, so the
+// block can be disconnected.
+type undo struct {
+	Hash     string   `json:"hash"`
+	PrevHash string   `json:"prev"`
+	Names    [][]byte `json:"names"`
+}
+
+// Node is the subset of nmcjson.Client used by an Index.
+type Node interface {
+	GetBlockCount(ctx context.Context) (int64, error)
+	GetBlockHash(ctx context.Context, height int64) (string, error)
+	GetBlockVerboseTx(ctx context.Context, hash string) (*btcjson.GetBlockVerboseTxResult, error)
+	ScanAll(ctx context.Context, opts *nmcjson.ScanOptions) *nmcjson.NameScanner
+}
+
+// Enforce that nmcjson.Client satisfies the Node interface.
+var _ Node = (*nmcjson.Client)(nil)
+
+// Index is a local copy of the name database of a node, kept in a Store and
+// serving name_show and name_history lookups without asking the node.
+type Index struct {
+	node   Node
+	store  Store
+	opts   Options
+	params *nmcjson.Params
+
+	syncMtx sync.Mutex // serialises Sync and Rebuild
+
+	mtx    sync.RWMutex
+	tip    tip
+	loaded bool
+}
+
+// New returns an Index kept in store which is synchronised from node.  A
+// store which already holds an index resumes from its sync height, or
+// resumes its interrupted initial load, on the next Sync.
+func New(node Node, store Store, opts *Options) (*Index, error) {
+	ix := &Index{node: node, store: store}
+	if opts != nil {
+		ix.opts = *opts
+	}
+	if ix.opts.Params == nil {
+		ix.opts.Params = &nmcjson.MainNetParams
+	}
+	ix.params = ix.opts.Params
+	if ix.opts.MaxReorgDepth <= 0 {
+		ix.opts.MaxReorgDepth = DefaultMaxReorgDepth
+	}
+	if ix.opts.PageSize == 0 {
+		ix.opts.PageSize = nmcjson.DefaultScanPageSize
+	}
+	var t tip
+	ok, err := ix.getJSON(keyTip, &t)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		ix.setTip(t)
+	}
+	return ix, nil
+}
+
+// Height returns the height of the last block applied to the index, or -1
+// before the initial load completed.
+func (ix *Index) Height() int64 {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	if !ix.loaded {
+		return -1
+	}
+	return ix.tip.Height
+}
+
+// NameShow returns the current value of the raw name, like the name_show
+// command of the node at the sync height.  Names which are not in the index
+// return nmcjson.ErrNameNotFound.  ctx is unused; it lets an Index stand in
+// for an nmcjson.Client.
+func (ix *Index) NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error) {
+	height, err := ix.loadedHeight()
+	if err != nil {
+		return nil, err
+	}
+	history, err := ix.history(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%w: %q", nmcjson.ErrNameNotFound, name)
+	}
+	result := ix.showResult(name, history[len(history)-1], height)
+	return &result, nil
+}
+
+// NameHistory returns every value of the raw name, oldest first, like the
+// name_history command.  The history of a name starts with the value found
+// by the initial load.
+func (ix *Index) NameHistory(ctx context.Context, name string) ([]nmcjson.NameHistoryResult, error) {
+	height, err := ix.loadedHeight()
+	if err != nil {
+		return nil, err
+	}
+	history, err := ix.history(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%w: %q", nmcjson.ErrNameNotFound, name)
+	}
+	results := make([]nmcjson.NameHistoryResult, 0, len(history))
+	for _, e := range history {
+		results = append(results, nmcjson.NameHistoryResult(ix.showResult(name, e, height)))
+	}
+	return results, nil
+}
+
+// showResult returns the name_show view of e at height.
+func (ix *Index) showResult(name string, e entry, height int64) nmcjson.NameShowResult {
+	expiresIn := ix.params.ExpiresIn(e.Height, height)
+	return nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{
+			Name:  name,
+			Value: string(e.Value),
+		},
+		Txid:      e.Txid,
+		Vout:      e.Vout,
+		Address:   e.Address,
+		Height:    e.Height,
+		ExpiresIn: expiresIn,
+		Expired:   expiresIn <= 0,
+	}
+}
+
+// Sync brings the index up to the tip of the node.  The first Sync loads
+// every name with name_scan.  Later ones apply the name operations of the
+// new blocks, after disconnecting the blocks which were reorganised away.
+// The index is written after every page and block, so an interrupted Sync
+// continues where it stopped.
+func (ix *Index) Sync(ctx context.Context) error {
+	ix.syncMtx.Lock()
+	defer ix.syncMtx.Unlock()
+	if _, err := ix.loadedHeight(); err != nil {
+		if err := ix.load(ctx); err != nil {
+			return err
+		}
+	}
+	for {
+		count, err := ix.node.GetBlockCount(ctx)
+		if err != nil {
+			return err
+		}
+		err = ix.rewind(ctx, count)
+		if errors.Is(err, ErrReorgTooDeep) {
+			err = ix.rebuild(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		done, err := ix.advance(ctx, count)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// Follow calls Sync every interval until ctx is done or Sync fails.
+func (ix *Index) Follow(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ix.Sync(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebuild discards the index and loads it again from the node.
+func (ix *Index) Rebuild(ctx context.Context) error {
+	ix.syncMtx.Lock()
+	defer ix.syncMtx.Unlock()
+	return ix.rebuild(ctx)
+}
+
+// rebuild is Rebuild without the lock.
+func (ix *Index) rebuild(ctx context.Context) error {
+	b := new(Batch)
+	err := ix.store.ForEach("", func(key string, _ []byte) error {
+		b.Delete(key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ix.mtx.Lock()
+	ix.loaded = false
+	ix.mtx.Unlock()
+	if err := ix.store.Write(b); err != nil {
+		return err
+	}
+	return ix.load(ctx)
+}
+
+// load stores the current value of every name, found with name_scan, and
+// sets the sync height to the tip when the scan started.  Names updated
+// during the scan are stored with their newer value, which advance
+// reconciles with the blocks that follow.
+func (ix *Index) load(ctx context.Context) error {
+	var state loadState
+	resuming, err := ix.getJSON(keyLoad, &state)
+	if err != nil {
+		return err
+	}
+	if !resuming {
+		if state.Tip, err = ix.nodeTip(ctx); err != nil {
+			return err
+		}
+	}
+
+	scanner := ix.node.ScanAll(ctx, &nmcjson.ScanOptions{
+		After:    state.Cursor,
+		PageSize: ix.opts.PageSize,
+		Options: &nmcjson.NameScanOptions{
+			NameEncodingOptions: nmcjson.NameEncodingOptions{
+				NameEncoding:  nmcjson.EncodingHex,
+				ValueEncoding: nmcjson.EncodingHex,
+			},
+		},
+	})
+	b := new(Batch)
+	flush := func() error {
+		state.Cursor = scanner.Cursor()
+		if err := putJSON(b, keyLoad, &state); err != nil {
+			return err
+		}
+		err := ix.store.Write(b)
+		b = new(Batch)
+		return err
+	}
+	for scanner.Next() {
+		r := scanner.Result()
+		name, err := r.NameBytes()
+		if err != nil {
+			return err
+		}
+		value, err := r.ValueBytes()
+		if err != nil {
+			return err
+		}
+		e := entry{
+			Value:   value,
+			Txid:    r.Txid,
+			Vout:    r.Vout,
+			Address: r.Address,
+			Height:  r.Height,
+		}
+		if err := putJSON(b, prefixName+string(name), []entry{e}); err != nil {
+			return err
+		}
+		if b.Len() >= ix.opts.PageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b.Delete(keyLoad)
+	if err := putJSON(b, keyTip, &state.Tip); err != nil {
+		return err
+	}
+	if err := ix.store.Write(b); err != nil {
+		return err
+	}
+	ix.setTip(state.Tip)
+	return nil
+}
+
+// rewind disconnects the blocks at the top of the index which are no longer
+// in the chain of the node, whose tip is at height count.
+func (ix *Index) rewind(ctx context.Context, count int64) error {
+	for {
+		t := ix.currentTip()
+		if t.Height <= count {
+			hash, err := ix.node.GetBlockHash(ctx, t.Height)
+			if err != nil && !chainChanged(err) {
+				return err
+			}
+			// A chain which shrank below t since count was read
+			// no longer has t either.
+			if err == nil && hash == t.Hash {
+				return nil
+			}
+		}
+		if err := ix.disconnect(t); err != nil {
+			return err
+		}
+	}
+}
+
+// advance connects the blocks after the index tip up to height count.  It
+// returns false if the chain of the node changed meanwhile, so the caller
+// should rewind again.
+func (ix *Index) advance(ctx context.Context, count int64) (bool, error) {
+	for height := ix.currentTip().Height + 1; height <= count; height++ {
+		hash, err := ix.node.GetBlockHash(ctx, height)
+		if chainChanged(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		block, err := ix.node.GetBlockVerboseTx(ctx, hash)
+		if chainChanged(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if block.PreviousHash != ix.currentTip().Hash {
+			return false, nil
+		}
+		if err := ix.connect(block); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// chainChanged reports whether err shows the block requested from the node
+// was reorganised away since its hash or height was read.  namecoind replies
+// to getblockhash above its tip with RPC_INVALID_PARAMETER, and to getblock
+// of an unknown hash with RPC_INVALID_ADDRESS_OR_KEY.
+func chainChanged(err error) bool {
+	var rpcErr *nmcjson.RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == btcjson.ErrRPCInvalidParameter || rpcErr.Code == btcjson.ErrRPCBlockNotFound
+}
+
+// blockOp is a name operation of a block.
+type blockOp struct {
+	script *namescript.Script
+	txid   string
+	vout   int
+}
+
+// connect applies the name operations of block, which follows the index tip.
+func (ix *Index) connect(block *btcjson.GetBlockVerboseTxResult) error {
+	var names []string
+	ops := make(map[string][]blockOp)
+	for _, tx := range block.Tx {
+		for _, out := range tx.Vout {
+			script, err := hex.DecodeString(out.ScriptPubKey.Hex)
+			if err != nil {
+				continue
+			}
+			s, err := namescript.Decode(script)
+			if err != nil || s.Op == namescript.OpNameNew {
+				continue
+			}
+			name := string(s.Name)
+			if _, ok := ops[name]; !ok {
+				names = append(names, name)
+			}
+			ops[name] = append(ops[name], blockOp{script: s, txid: tx.Txid, vout: int(out.N)})
+		}
+	}
+
+	b := new(Batch)
+	u := undo{Hash: block.Hash, PrevHash: block.PreviousHash}
+	for _, name := range names {
+		history, err := ix.history(name)
+		if err != nil {
+			return err
+		}
+		nameOps := skipLoaded(history, ops[name], block.Height)
+		for _, op := range nameOps {
+			e := entry{
+				Value:  op.script.Value,
+				Txid:   op.txid,
+				Vout:   op.vout,
+				Height: block.Height,
+			}
+			// Name scripts which do not pay to an address are kept
+			// with an empty address, as namecoind does.
+			if addr, err := address.FromScript(op.script.AddressScript, ix.params); err == nil {
+				e.Address = addr.String()
+			}
+			history = append(history, e)
+			u.Names = append(u.Names, []byte(name))
+		}
+		if len(nameOps) != 0 {
+			if err := putJSON(b, prefixName+name, history); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := putJSON(b, undoKey(block.Height), &u); err != nil {
+		return err
+	}
+	if pruned := block.Height - ix.opts.MaxReorgDepth; pruned >= 0 {
+		b.Delete(undoKey(pruned))
+	}
+	t := tip{Height: block.Height, Hash: block.Hash}
+	if err := putJSON(b, keyTip, &t); err != nil {
+		return err
+	}
+	if err := ix.store.Write(b); err != nil {
+		return err
+	}
+	ix.setTip(t)
+	return nil
+}
+
+// skipLoaded returns the operations of a name in the block at height which
+// are not already reflected in its history.  Only a value stored by the
+// initial load can be as recent as the block: the operations up to and
+// including it are skipped, or all of them if it comes from a later block.
+func skipLoaded(history []entry, ops []blockOp, height int64) []blockOp {
+	if len(history) == 0 {
+		return ops
+	}
+	last := history[len(history)-1]
+	if last.Height < height {
+		return ops
+	}
+	for i, op := range ops {
+		if op.txid == last.Txid && op.vout == last.Vout {
+			return ops[i+1:]
+		}
+	}
+	if last.Height > height {
+		return nil
+	}
+	return ops
+}
+
+// disconnect undoes the block at t, the index tip.
+func (ix *Index) disconnect(t tip) error {
+	var u undo
+	ok, err := ix.getJSON(undoKey(t.Height), &u)
+	if err != nil {
+		return err
+	}
+	if !ok || u.Hash != t.Hash {
+		return ErrReorgTooDeep
+	}
+
+	b := new(Batch)
+	histories := make(map[string][]entry)
+	for i := len(u.Names) - 1; i >= 0; i-- {
+		name := string(u.Names[i])
+		history, ok := histories[name]
+		if !ok {
+			if history, err = ix.history(name); err != nil {
+				return err
+			}
+		}
+		if len(history) != 0 {
+			history = history[:len(history)-1]
+		}
+		histories[name] = history
+	}
+	for name, history := range histories {
+		if len(history) == 0 {
+			b.Delete(prefixName + name)
+			continue
+		}
+		if err := putJSON(b, prefixName+name, history); err != nil {
+			return err
+		}
+	}
+
+	b.Delete(undoKey(t.Height))
+	prev := tip{Height: t.Height - 1, Hash: u.PrevHash}
+	if err := putJSON(b, keyTip, &prev); err != nil {
+		return err
+	}
+	if err := ix.store.Write(b); err != nil {
+		return err
+	}
+	ix.setTip(prev)
+	return nil
+}
+
+// nodeTip returns the tip of the node.
+func (ix *Index) nodeTip(ctx context.Context) (tip, error) {
+	count, err := ix.node.GetBlockCount(ctx)
+	if err != nil {
+		return tip{}, err
+	}
+	hash, err := ix.node.GetBlockHash(ctx, count)
+	if err != nil {
+		return tip{}, err
+	}
+	return tip{Height: count, Hash: hash}, nil
+}
+
+// history returns the stored entries of the raw name.
+func (ix *Index) history(name string) ([]entry, error) {
+	var history []entry
+	if _, err := ix.getJSON(prefixName+name, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// loadedHeight returns the sync height, or ErrNotLoaded.
+func (ix *Index) loadedHeight() (int64, error) {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	if !ix.loaded {
+		return 0, ErrNotLoaded
+	}
+	return ix.tip.Height, nil
+}
+
+// currentTip returns the index tip.
+func (ix *Index) currentTip() tip {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	return ix.tip
+}
+
+// setTip records t, which was just written, as the index tip.
+func (ix *Index) setTip(t tip) {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+	ix.tip = t
+	ix.loaded = true
+}
+
+// getJSON decodes the value of key into v and reports whether key is set.
+func (ix *Index) getJSON(key string, v interface{}) (bool, error) {
+	value, err := ix.store.Get(key)
+	if err != nil || value == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		return false, fmt.Errorf("corrupt index entry %q: %v", key, err)
+	}
+	return true, nil
+}
+
+// putJSON adds a write of v, encoded as JSON, to key to b.
+func putJSON(b *Batch, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b.Put(key, value)
+	return nil
+}
+
+// undoKey returns the key of the undo data of the block at height.
+func undoKey(height int64) string {
+	return fmt.Sprintf("%s%016x", prefixUndo, height)
+}
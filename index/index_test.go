@@ -0,0 +1,248 @@
+package index
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/nmctest"
+)
+
+// testChain is an nmctest server with a client and helpers to change names.
+type testChain struct {
+	t      *testing.T
+	server *nmctest.Server
+	client *nmcjson.Client
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+	s := nmctest.NewServer(nil)
+	t.Cleanup(s.Close)
+	client, err := nmcjson.NewClient(s.ConnConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testChain{t: t, server: s, client: client}
+}
+
+// register registers name with value, mining the blocks it needs.
+func (c *testChain) register(name, value string) {
+	c.t.Helper()
+	ctx := context.Background()
+	nn, err := c.client.NameNew(ctx, name)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.server.Generate(12)
+	if _, err := c.client.NameFirstUpdate(ctx, name, nn.Rand, nn.Txid, value, ""); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// update sets the value of name in the block at the tip.
+func (c *testChain) update(name, value string) {
+	c.t.Helper()
+	if _, err := c.client.NameUpdate(context.Background(), name, value, ""); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// check compares the lookups of ix for names with those of the node.
+func (c *testChain) check(ix *Index, names ...string) {
+	c.t.Helper()
+	ctx := context.Background()
+	if got, want := ix.Height(), c.server.Height(); got != want {
+		c.t.Fatalf("index height: got %d, want %d", got, want)
+	}
+	for _, name := range names {
+		want, err := c.client.NameShow(ctx, name)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		got, err := ix.NameShow(ctx, name)
+		if err != nil {
+			c.t.Fatalf("NameShow(%q): %v", name, err)
+		}
+		if got.Value != want.Value || got.Txid != want.Txid || got.Height != want.Height ||
+			got.Address != want.Address || got.ExpiresIn != want.ExpiresIn {
+			c.t.Fatalf("NameShow(%q):\ngot  %+v\nwant %+v", name, got, want)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	c := newTestChain(t)
+	c.register("d/loaded", "first")
+	c.server.Generate(1)
+
+	ix, err := New(c.client, NewMemStore(), &Options{Params: c.server.Params()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ix.NameShow(ctx, "d/loaded"); err != ErrNotLoaded {
+		t.Fatalf("NameShow before Sync: got %v, want %v", err, ErrNotLoaded)
+	}
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.check(ix, "d/loaded")
+
+	// Mining into the loaded tip would change its hash and force a
+	// rebuild, so the changes start in a new block.
+	c.server.Generate(1)
+	c.register("d/followed", "a")
+	c.update("d/loaded", "second")
+	c.server.Generate(3)
+	c.update("d/followed", "b")
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.check(ix, "d/loaded", "d/followed")
+
+	history, err := ix.NameHistory(ctx, "d/followed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Value != "a" || history[1].Value != "b" {
+		t.Fatalf("NameHistory: got %+v, want values a, b", history)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int64
+	}{
+		{"tip", 1},
+		{"below updates", 3},
+		{"below registration", 8},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newTestChain(t)
+			c.register("d/old", "kept")
+			c.server.Generate(20)
+
+			ix, err := New(c.client, NewMemStore(), &Options{Params: c.server.Params()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ix.Sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+			nn, err := c.client.NameNew(ctx, "d/new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.server.Generate(12)
+			if _, err := c.client.NameFirstUpdate(ctx, "d/new", nn.Rand, nn.Txid, "a", ""); err != nil {
+				t.Fatal(err)
+			}
+			c.server.Generate(4)
+			c.update("d/old", "undone")
+			c.server.Generate(1)
+			c.update("d/new", "b")
+			if err := ix.Sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			c.server.Reorg(test.depth)
+			if err := ix.Sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+			c.check(ix, "d/old")
+			if _, err := c.client.NameShow(ctx, "d/new"); err == nil {
+				c.check(ix, "d/new")
+			} else if _, err := ix.NameShow(ctx, "d/new"); err == nil {
+				t.Fatal("d/new was reorganised away but is still indexed")
+			}
+
+			c.update("d/old", "replayed")
+			if err := ix.Sync(ctx); err != nil {
+				t.Fatal(err)
+			}
+			c.check(ix, "d/old")
+		})
+	}
+}
+
+// shrinkingNode makes the chain of the node appear to lose its last blocks
+// right after the block count is read, as when namecoind switches to a
+// shorter chain with more work.
+type shrinkingNode struct {
+	*nmcjson.Client
+
+	// tip is the height the chain appears to have, or -1 when it is
+	// not shrunk.
+	tip int64
+
+	// stale is the number of block counts still reporting the height
+	// before the shrink.
+	stale int
+}
+
+func (n *shrinkingNode) GetBlockCount(ctx context.Context) (int64, error) {
+	if n.tip >= 0 && n.stale == 0 {
+		return n.tip, nil
+	}
+	if n.stale > 0 {
+		n.stale--
+	}
+	return n.Client.GetBlockCount(ctx)
+}
+
+func (n *shrinkingNode) GetBlockHash(ctx context.Context, height int64) (string, error) {
+	if n.tip >= 0 && height > n.tip {
+		return "", nmcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Block height out of range")
+	}
+	return n.Client.GetBlockHash(ctx, height)
+}
+
+func TestChainShrinks(t *testing.T) {
+	ctx := context.Background()
+	c := newTestChain(t)
+	c.register("d/name", "a")
+	c.server.Generate(5)
+
+	node := &shrinkingNode{Client: c.client, tip: -1}
+	ix, err := New(node, NewMemStore(), &Options{Params: c.server.Params()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.server.Generate(1)
+	c.update("d/name", "b")
+	c.server.Generate(2)
+	top := c.server.Height()
+
+	// The first Sync sees the new blocks counted but not served.
+	node.tip, node.stale = top-2, 1
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatalf("Sync during shrink: %v", err)
+	}
+	if got := ix.Height(); got != top-2 {
+		t.Fatalf("index height after shrink: got %d, want %d", got, top-2)
+	}
+
+	// The next Sync sees the indexed tip counted but not served, and
+	// disconnects it.
+	node.tip, node.stale = top-3, 1
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatalf("Sync during second shrink: %v", err)
+	}
+	if got := ix.Height(); got != top-3 {
+		t.Fatalf("index height after second shrink: got %d, want %d", got, top-3)
+	}
+
+	node.tip = -1
+	if err := ix.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.check(ix, "d/name")
+}
@@ -0,0 +1,393 @@
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store is the embedded key-value store holding an index.  Implementations
+// must be safe for concurrent use and must apply each Batch atomically, so
+// that the index is consistent after a crash.  The index never stores empty
+// values.
+type Store interface {
+	// Get returns the value of key, or nil if it is not set.
+	Get(key string) ([]byte, error)
+
+	// Write applies b atomically.
+	Write(b *Batch) error
+
+	// ForEach calls fn with every key starting with prefix and its value,
+	// in no particular order.  fn must not write to the store.
+	ForEach(prefix string, fn func(key string, value []byte) error) error
+}
+
+// batchOp is a single write of a Batch.
+type batchOp struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+// Batch is a set of writes applied atomically by Store.Write, in the order
+// they were added.
+type Batch struct {
+	ops []batchOp
+}
+
+// Put sets key to value.
+func (b *Batch) Put(key string, value []byte) {
+	b.ops = append(b.ops, batchOp{key: key, value: value})
+}
+
+// Delete removes key.
+func (b *Batch) Delete(key string) {
+	b.ops = append(b.ops, batchOp{key: key, delete: true})
+}
+
+// Len returns the number of writes in b.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// memData is a map of keys to values shared by MemStore and FileStore.
+type memData map[string][]byte
+
+// apply applies the writes of b to d.
+func (d memData) apply(b *Batch) {
+	for _, op := range b.ops {
+		if op.delete {
+			delete(d, op.key)
+		} else {
+			d[op.key] = op.value
+		}
+	}
+}
+
+// forEach calls fn with every key of d starting with prefix.
+func (d memData) forEach(prefix string, fn func(key string, value []byte) error) error {
+	for key, value := range d {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MemStore is a Store which only keeps its data in memory, for indexes which
+// are rebuilt on every start and for tests.
+type MemStore struct {
+	mtx  sync.RWMutex
+	data memData
+}
+
+// Enforce that MemStore satisfies the Store interface.
+var _ Store = (*MemStore)(nil)
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{data: make(memData)}
+}
+
+// Get returns the value of key, or nil if it is not set.
+func (s *MemStore) Get(key string) ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.data[key], nil
+}
+
+// Write applies b atomically.
+func (s *MemStore) Write(b *Batch) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.data.apply(b)
+	return nil
+}
+
+// ForEach calls fn with every key starting with prefix and its value.
+func (s *MemStore) ForEach(prefix string, fn func(key string, value []byte) error) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.data.forEach(prefix, fn)
+}
+
+const (
+	// recordHeaderSize is the size of the length and checksum preceding
+	// each record of a FileStore log.
+	recordHeaderSize = 8
+
+	// opPut and opDelete are the kinds of the writes in a record.
+	opPut    = 1
+	opDelete = 2
+
+	// minCompactSize is the log size below which a FileStore is never
+	// compacted.
+	minCompactSize = 1 << 20
+)
+
+// FileStore is a Store kept in memory and persisted to a single append-only
+// log file.  Every batch is appended to the log as one checksummed record
+// and synced to disk before Write returns.  A record torn by a crash fails
+// its checksum and is discarded when the file is opened again, so the store
+// always reopens at the last completed batch.  The log is rewritten as a
+// single record once it grows to twice the size of the live data.
+type FileStore struct {
+	mtx  sync.RWMutex
+	path string
+	file *os.File
+	data memData
+	size int64 // size of the log
+	live int64 // size of data written as records
+}
+
+// Enforce that FileStore satisfies the Store interface.
+var _ Store = (*FileStore)(nil)
+
+// OpenFileStore opens the FileStore persisted at path, creating it if it does
+// not exist.  The caller must call Close when finished.
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{path: path, file: file, data: make(memData)}
+	if err := s.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// replay loads the log into memory.  A torn record at the end of the log is
+// truncated away.
+func (s *FileStore) replay() error {
+	log, err := io.ReadAll(s.file)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	for {
+		b, n := decodeRecord(log[offset:])
+		if b == nil {
+			break
+		}
+		s.applyLive(b)
+		offset += n
+	}
+	if offset != int64(len(log)) {
+		if err := s.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	s.size = offset
+	return nil
+}
+
+// applyLive applies b to the data and keeps track of its size.
+func (s *FileStore) applyLive(b *Batch) {
+	for _, op := range b.ops {
+		if old, ok := s.data[op.key]; ok {
+			s.live -= opSize(op.key, old)
+		}
+		if !op.delete {
+			s.live += opSize(op.key, op.value)
+		}
+	}
+	s.data.apply(b)
+}
+
+// Get returns the value of key, or nil if it is not set.
+func (s *FileStore) Get(key string) ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.data[key], nil
+}
+
+// ForEach calls fn with every key starting with prefix and its value.
+func (s *FileStore) ForEach(prefix string, fn func(key string, value []byte) error) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.data.forEach(prefix, fn)
+}
+
+// Write appends b to the log, syncs it and applies it.
+func (s *FileStore) Write(b *Batch) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	record := encodeRecord(b)
+	_, err := s.file.Write(record)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// Drop whatever part of the record was written, so later
+		// records are not lost behind it.
+		s.file.Truncate(s.size)
+		s.file.Seek(s.size, io.SeekStart)
+		return err
+	}
+	s.size += int64(len(record))
+	s.applyLive(b)
+	if s.size > minCompactSize && s.size > 2*s.live {
+		// The batch is already durable, so a failed compaction only
+		// leaves the log as it is until the next write.
+		s.compact()
+	}
+	return nil
+}
+
+// compact rewrites the log as a single record holding the live data.  The
+// new log replaces the old one with a rename, so a crash leaves either.
+func (s *FileStore) compact() error {
+	var b Batch
+	for key, value := range s.data {
+		b.Put(key, value)
+	}
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	record := encodeRecord(&b)
+	if _, err := tmp.Write(record); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	s.file.Close()
+	s.file = tmp
+	s.size = int64(len(record))
+	return nil
+}
+
+// Close closes the log file.
+func (s *FileStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.file == nil {
+		return os.ErrClosed
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// opSize returns the size of a put of key and value in a record.
+func opSize(key string, value []byte) int64 {
+	return int64(1 + 2*binary.MaxVarintLen64 + len(key) + len(value))
+}
+
+// encodeRecord returns b as a log record: the length and CRC-32 of the
+// payload followed by the payload, the writes of b.
+func encodeRecord(b *Batch) []byte {
+	var payload bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+	for _, op := range b.ops {
+		kind := byte(opPut)
+		if op.delete {
+			kind = opDelete
+		}
+		payload.WriteByte(kind)
+		payload.Write(n[:binary.PutUvarint(n[:], uint64(len(op.key)))])
+		payload.WriteString(op.key)
+		if !op.delete {
+			payload.Write(n[:binary.PutUvarint(n[:], uint64(len(op.value)))])
+			payload.Write(op.value)
+		}
+	}
+	record := make([]byte, recordHeaderSize, recordHeaderSize+payload.Len())
+	binary.LittleEndian.PutUint32(record[0:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	return append(record, payload.Bytes()...)
+}
+
+// decodeRecord decodes the record at the start of log and returns it with
+// its size.  It returns nil if log does not start with a complete, intact
+// record.
+func decodeRecord(log []byte) (*Batch, int64) {
+	if len(log) < recordHeaderSize {
+		return nil, 0
+	}
+	size := int64(binary.LittleEndian.Uint32(log[0:4]))
+	if int64(len(log)-recordHeaderSize) < size {
+		return nil, 0
+	}
+	payload := log[recordHeaderSize : recordHeaderSize+size]
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(log[4:8]) {
+		return nil, 0
+	}
+	b, err := decodePayload(payload)
+	if err != nil {
+		return nil, 0
+	}
+	return b, recordHeaderSize + size
+}
+
+// errBadRecord indicates a record whose checksum matches but which can not
+// be decoded.
+var errBadRecord = errors.New("malformed record")
+
+// decodePayload decodes the writes of a record.
+func decodePayload(payload []byte) (*Batch, error) {
+	b := new(Batch)
+	r := bytes.NewReader(payload)
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, errBadRecord
+		}
+		buf := make([]byte, n)
+		r.Read(buf)
+		return buf, nil
+	}
+	for r.Len() > 0 {
+		kind, _ := r.ReadByte()
+		key, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case opPut:
+			value, err := readBytes()
+			if err != nil {
+				return nil, err
+			}
+			b.Put(string(key), value)
+		case opDelete:
+			b.Delete(string(key))
+		default:
+			return nil, fmt.Errorf("%w: unknown write %d", errBadRecord, kind)
+		}
+	}
+	return b, nil
+}
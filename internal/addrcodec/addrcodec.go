@@ -0,0 +1,171 @@
+// Package addrcodec converts Namecoin addresses to and from their hashes and
+// output scripts.  It is the codec behind the address package, kept free of
+// other nmcjson packages so that nmcjson can validate addresses with it too.
+package addrcodec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// Opcodes of the standard address scripts.
+const (
+	op0           = 0x00
+	opDup         = 0x76
+	opEqual       = 0x87
+	opEqualVerify = 0x88
+	opHash160     = 0xa9
+	opCheckSig    = 0xac
+)
+
+// HashLength is the length of the hash of P2PKH and P2SH addresses.
+const HashLength = 20
+
+var (
+	// ErrUnknownFormat indicates a string which is not an address of a
+	// supported kind.
+	ErrUnknownFormat = errors.New("unknown address format")
+
+	// ErrWrongNetwork indicates an address of another network than the
+	// one it was decoded for.
+	ErrWrongNetwork = errors.New("address is for another network")
+
+	// ErrNonStandardScript indicates a script which does not pay to an
+	// address.
+	ErrNonStandardScript = errors.New("script does not pay to an address")
+)
+
+// Kind is the kind of an address.
+type Kind int
+
+// Kinds of addresses.
+const (
+	PubKeyHash Kind = iota
+	ScriptHash
+	Witness
+)
+
+// Net is the address encoding of a network.
+type Net struct {
+	Name             string
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+	Bech32HRP        string
+}
+
+// Decode parses addr as an address of net and returns its kind along with
+// its hash or witness program.
+func Decode(addr string, net Net) (Kind, []byte, error) {
+	if hrp, data, err := bech32.Decode(addr); err == nil {
+		return decodeWitness(addr, strings.ToLower(hrp), data, net)
+	}
+	payload, version, err := base58.CheckDecode(addr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %q", ErrUnknownFormat, addr)
+	}
+	if len(payload) != HashLength {
+		return 0, nil, fmt.Errorf("%w: %q has a %d byte payload", ErrUnknownFormat, addr, len(payload))
+	}
+	switch version {
+	case net.PubKeyHashAddrID:
+		return PubKeyHash, payload, nil
+	case net.ScriptHashAddrID:
+		return ScriptHash, payload, nil
+	}
+	return 0, nil, fmt.Errorf("%w: %q is not a %s address", ErrWrongNetwork, addr, net.Name)
+}
+
+// decodeWitness decodes the parts of the bech32 address addr.
+func decodeWitness(addr, hrp string, data []byte, net Net) (Kind, []byte, error) {
+	if hrp != net.Bech32HRP {
+		return 0, nil, fmt.Errorf("%w: %q is not a %s address", ErrWrongNetwork, addr, net.Name)
+	}
+	if len(data) == 0 || data[0] != 0 {
+		return 0, nil, fmt.Errorf("%w: %q is not a segwit version 0 address", ErrUnknownFormat, addr)
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %q: %v", ErrUnknownFormat, addr, err)
+	}
+	if err := CheckLength(Witness, program); err != nil {
+		return 0, nil, fmt.Errorf("%w: %q: %v", ErrUnknownFormat, addr, err)
+	}
+	return Witness, program, nil
+}
+
+// CheckLength checks that data has the length of a hash or witness program
+// of an address of kind.
+func CheckLength(kind Kind, data []byte) error {
+	switch kind {
+	case PubKeyHash:
+		if len(data) != HashLength {
+			return fmt.Errorf("pubkey hash is %d bytes, expected %d", len(data), HashLength)
+		}
+	case ScriptHash:
+		if len(data) != HashLength {
+			return fmt.Errorf("script hash is %d bytes, expected %d", len(data), HashLength)
+		}
+	case Witness:
+		if len(data) != 20 && len(data) != 32 {
+			return fmt.Errorf("witness program is %d bytes, expected 20 or 32", len(data))
+		}
+	}
+	return nil
+}
+
+// Encode returns the address of kind with the hash or witness program data
+// on net.  data must have been checked with CheckLength.
+func Encode(kind Kind, data []byte, net Net) string {
+	switch kind {
+	case PubKeyHash:
+		return base58.CheckEncode(data, net.PubKeyHashAddrID)
+	case ScriptHash:
+		return base58.CheckEncode(data, net.ScriptHashAddrID)
+	}
+	converted, err := bech32.ConvertBits(data, 8, 5, true)
+	if err != nil {
+		// Unreachable: any bytes convert with padding.
+		panic(err)
+	}
+	s, err := bech32.Encode(net.Bech32HRP, append([]byte{0}, converted...))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Script returns the output script paying to the address of kind with the
+// hash or witness program data.
+func Script(kind Kind, data []byte) []byte {
+	switch kind {
+	case PubKeyHash:
+		script := append([]byte{opDup, opHash160, HashLength}, data...)
+		return append(script, opEqualVerify, opCheckSig)
+	case ScriptHash:
+		script := append([]byte{opHash160, HashLength}, data...)
+		return append(script, opEqual)
+	}
+	return append([]byte{op0, byte(len(data))}, data...)
+}
+
+// FromScript returns the kind and the hash or witness program of the address
+// paid by script, a P2PKH, P2SH or segwit version 0 output script.
+func FromScript(script []byte) (Kind, []byte, error) {
+	switch {
+	case len(script) == 25 && bytes.HasPrefix(script, []byte{opDup, opHash160, HashLength}) &&
+		script[23] == opEqualVerify && script[24] == opCheckSig:
+		return PubKeyHash, script[3:23], nil
+	case len(script) == 23 && bytes.HasPrefix(script, []byte{opHash160, HashLength}) &&
+		script[22] == opEqual:
+		return ScriptHash, script[2:22], nil
+	case (len(script) == 22 || len(script) == 34) && script[0] == op0 &&
+		int(script[1]) == len(script)-2:
+		return Witness, script[2:], nil
+	}
+	return 0, nil, fmt.Errorf("%w: %x", ErrNonStandardScript, script)
+}
@@ -2,844 +2,312 @@ package nmcjson
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
+
 	"github.com/btcsuite/btcd/btcjson"
 )
 
-// NameNewCmd is a type handling custom marshaling and
-// unmarshaling of name_new JSON RPC commands.
+// NameNewCmd defines the name_new JSON-RPC command.
 type NameNewCmd struct {
-	id   interface{}
-	Name string
-}
-
-// Enforce that NameNewCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameNewCmd{}
-
-// NewNameNewCmd creates a new NameNewCmd.
-func NewNameNewCmd(id interface{}, name string) (*NameNewCmd, error) {
-	return &NameNewCmd{
-		id:   id,
-		Name: name,
-	}, nil
-}
-
-// NameNewFromRaw is a RawCmdParser.
-func NameNewFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var name string
-	err := json.Unmarshal(rawCmd.Params[0], &name)
-	if err != nil {
-		return NameNewCmd{}, err
-	}
-	return NewNameNewCmd(rawCmd.Id, name)
-}
-
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameNewCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameNewCmd) Method() string {
-	return "name_new"
-}
-
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameNewCmd) MarshalJSON() ([]byte, error) {
-	params := []interface{}{
-		cmd.Name,
-	}
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
+	Name    string
+	Options *NameTxOptions
+}
+
+// NewNameNewCmd returns a new instance which can be used to issue a name_new
+// JSON-RPC command.  The command is checked with Validate against any
+// network; to skip the checks, build a NameNewCmd directly.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNameNewCmd(name string, options *NameTxOptions) (*NameNewCmd, error) {
+	cmd := &NameNewCmd{
+		Name:    name,
+		Options: options,
+	}
+	if err := cmd.Validate(nil); err != nil {
 		return nil, err
 	}
-	return json.Marshal(raw)
+	return cmd, nil
 }
 
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameNewCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-
-	if len(r.Params) != 1 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	newCmd, err := NameNewFromRaw(&r)
-	if err != nil {
-		return err
-	}
-	cmd = newCmd.(NameNewCmd)
-	return nil
-}
-
-// NameUpdateCmd is a type handling custom marshaling and
-// unmarshaling of name_update JSON RPC commands.
+// NameUpdateCmd defines the name_update JSON-RPC command.
 type NameUpdateCmd struct {
-	id        interface{}
-	Name      string
-	Value     string
-	ToAddress string
-}
-
-// Enforce that NameUpdateCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameUpdateCmd{}
-
-// NewNameUpdateCmd creates a new NameUpdateCmd.
-func NewNameUpdateCmd(id interface{}, name string, value string, optArgs ...interface{}) (*NameUpdateCmd, error) {
-	var toAddress string
-	if len(optArgs) > 1 {
-		return nil, btcjson.ErrWrongNumberOfParams
-	}
-
-	if len(optArgs) > 0 {
-		a, ok := optArgs[0].(string)
-		if !ok {
-			return nil, errors.New("optional argument toaddress is not a string")
-		}
-		toAddress = a
-	}
-	return &NameUpdateCmd{
-		id:        id,
-		Name:      name,
-		Value:     value,
-		ToAddress: toAddress,
-	}, nil
-}
-
-// NameUpdateFromRaw is a RawCmdParser.
-func NameUpdateFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var name string
-	var value string
-	var toAddress string
-	params := make([]interface{}, 0, 1)
-	err := json.Unmarshal(rawCmd.Params[0], &name)
-	if err != nil {
-		return NameUpdateCmd{}, err
-	}
-	err = json.Unmarshal(rawCmd.Params[1], &value)
-	if err != nil {
-		return NameUpdateCmd{}, err
-	}
-	if len(rawCmd.Params) > 2 {
-		err = json.Unmarshal(rawCmd.Params[2], &toAddress)
-		if err != nil {
-			return NameUpdateCmd{}, err
-		}
-		params = append(params, toAddress)
-	}
-
-	return NewNameUpdateCmd(rawCmd.Id, name, value, params)
-}
-
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameUpdateCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameUpdateCmd) Method() string {
-	return "name_update"
-}
-
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameUpdateCmd) MarshalJSON() ([]byte, error) {
-	params := make([]interface{}, 0, 3)
-	params = append(params, cmd.Name)
-	params = append(params, cmd.Value)
-	if cmd.ToAddress != "" {
-		params = append(params, cmd.ToAddress)
-	}
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
+	Name    string
+	Value   string
+	Options *NameTxOptions
+}
+
+// NewNameUpdateCmd returns a new instance which can be used to issue a
+// name_update JSON-RPC command.  The name is transferred when
+// options.DestAddress is set.  The command is checked with Validate against
+// any network; to skip the checks, build a NameUpdateCmd directly.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNameUpdateCmd(name, value string, options *NameTxOptions) (*NameUpdateCmd, error) {
+	cmd := &NameUpdateCmd{
+		Name:    name,
+		Value:   value,
+		Options: options,
+	}
+	if err := cmd.Validate(nil); err != nil {
 		return nil, err
 	}
-	return json.Marshal(raw)
-}
-
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameUpdateCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-	if len(r.Params) > 3 || len(r.Params) < 2 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	newCmd, err := NameUpdateFromRaw(&r)
-	if err != nil {
-		return err
-	}
-	cmd = newCmd.(NameUpdateCmd)
-	return nil
+	return cmd, nil
 }
 
-// NameFirstUpdateCmd is a type handling custom marshaling and
-// unmarshaling of name_firstupdate JSON RPC commands.
+// NameFirstUpdateCmd defines the name_firstupdate JSON-RPC command.
 type NameFirstUpdateCmd struct {
-	id        interface{}
-	Name      string
-	Rand      string
-	Txid      string
-	Value     string
-	ToAddress string
-}
-
-// Enforce that NameFirstUpdateCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameFirstUpdateCmd{}
-
-// NewNameFirstUpdateCmd creates a new NameFirstUpdateCmd.
-func NewNameFirstUpdateCmd(id interface{}, name, rand, value string, optArgs ...interface{}) (*NameFirstUpdateCmd, error) {
-	var txId string
-	var toAddress string
-	if len(optArgs) > 2 {
-		return nil, btcjson.ErrWrongNumberOfParams
-	}
-
-	if len(optArgs) > 0 {
-		a, ok := optArgs[0].(string)
-		if !ok {
-			return nil, errors.New("optional argument txid is not a string")
-		}
-		txId = a
-	}
-	if len(optArgs) > 1 {
-		b, ok := optArgs[1].(string)
-		if !ok {
-			return nil, errors.New("optional argument toaddress is not a string")
-		}
-		toAddress = b
-	}
-	return &NameFirstUpdateCmd{
-		Name:      name,
-		Rand:      rand,
-		Txid:      txId,
-		Value:     value,
-		ToAddress: toAddress,
-	}, nil
-
-}
-
-// NameFirstUpdateFromRaw is a RawCmdParser.
-func NameFirstUpdateFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var name string
-	var rand string
-	var value string
-	var txId string
-	var toAddress string
-	params := make([]interface{}, 0, 2)
-	if err := json.Unmarshal(rawCmd.Params[0], &name); err != nil {
-		return NameFirstUpdateCmd{}, err
-	}
-	if err := json.Unmarshal(rawCmd.Params[1], &rand); err != nil {
-		return NameFirstUpdateCmd{}, err
-	}
-	if err := json.Unmarshal(rawCmd.Params[2], &value); err != nil {
-		return NameFirstUpdateCmd{}, err
-	}
-
-	if len(rawCmd.Params) > 3 {
-		if err := json.Unmarshal(rawCmd.Params[3], &txId); err != nil {
-			return NameFirstUpdateCmd{}, err
-		}
-		params = append(params, txId)
-	}
-	if len(rawCmd.Params) > 4 {
-		if err := json.Unmarshal(rawCmd.Params[4], &toAddress); err != nil {
-			return NameFirstUpdateCmd{}, err
-		}
-		params = append(params, toAddress)
-	}
-
-	return NewNameFirstUpdateCmd(rawCmd.Id, name, rand, value, params)
-
-}
-
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameFirstUpdateCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameFirstUpdateCmd) Method() string {
-	return "name_firstupdate"
-}
-
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameFirstUpdateCmd) MarshalJSON() ([]byte, error) {
-	params := make([]interface{}, 0, 5)
-	params = append(params, cmd.Name)
-	params = append(params, cmd.Rand)
-	if cmd.Txid != "" {
-		params = append(params, cmd.Txid)
-	}
-	params = append(params, cmd.Value)
-	if cmd.ToAddress != "" {
-		params = append(params, cmd.ToAddress)
-	}
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
+	Name    string
+	Rand    string
+	Txid    string
+	Value   string
+	Options *NameTxOptions
+}
+
+// NewNameFirstUpdateCmd returns a new instance which can be used to issue a
+// name_firstupdate JSON-RPC command.  rand and txid are the values returned
+// by the name_new which reserved name.  The command is checked with Validate
+// against any network; to skip the checks, build a NameFirstUpdateCmd
+// directly.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNameFirstUpdateCmd(name, rand, txid, value string, options *NameTxOptions) (*NameFirstUpdateCmd, error) {
+	cmd := &NameFirstUpdateCmd{
+		Name:    name,
+		Rand:    rand,
+		Txid:    txid,
+		Value:   value,
+		Options: options,
+	}
+	if err := cmd.Validate(nil); err != nil {
 		return nil, err
 	}
-	return json.Marshal(raw)
+	return cmd, nil
 }
 
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameFirstUpdateCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-	if len(r.Params) < 3 || len(r.Params) > 5 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-	newCmd, err := NameFirstUpdateFromRaw(&r)
-	if err != nil {
-		return err
-	}
-	cmd = newCmd.(NameFirstUpdateCmd)
-	return nil
-}
-
-// NameShowCmd is a type handling custom marshaling and
-// unmarshaling of name_show JSON RPC commands.
+// NameShowCmd defines the name_show JSON-RPC command.
 type NameShowCmd struct {
-	id   interface{}
-	Name string
+	Name    string
+	Options *NameShowOptions
 }
 
-// Enforce that NameShowCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameShowCmd{}
-
-// NewNameShowCmd creates a new NameShowCmd.
-func NewNameShowCmd(id interface{}, name string) (*NameShowCmd, error) {
+// NewNameShowCmd returns a new instance which can be used to issue a
+// name_show JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNameShowCmd(name string, options *NameShowOptions) *NameShowCmd {
 	return &NameShowCmd{
-		id:   id,
-		Name: name,
-	}, nil
-}
-
-// NameShowFromRaw is a RawCmdParser.
-func NameShowFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var nameStr string
-	err := json.Unmarshal(rawCmd.Params[0], &nameStr)
-	if err != nil {
-		return NameShowCmd{}, err
-	}
-
-	newCmd, err := NewNameShowCmd(rawCmd.Id, nameStr)
-	if err != nil {
-		return *newCmd, err
+		Name:    name,
+		Options: options,
 	}
-	return *newCmd, nil
 }
 
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameShowCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameShowCmd) Method() string {
-	return "name_show"
-}
-
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameShowCmd) MarshalJSON() ([]byte, error) {
-	params := []interface{}{
-		cmd.Name,
-	}
-
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(raw)
-}
-
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameShowCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-
-	if len(r.Params) != 1 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	var name string
-	if err := json.Unmarshal(r.Params[0], &name); err != nil {
-		return fmt.Errorf("name parameter must be a string: %v", err)
-	}
-
-	newCmd, err := NewNameShowCmd(r.Id, name)
-	if err != nil {
-		return err
-	}
-	cmd = *newCmd
-	return nil
-}
-
-// NameListCmd is a type handling custom marshaling and
-// unmarshaling of name_list JSON RPC commands.
+// NameListCmd defines the name_list JSON-RPC command.
 type NameListCmd struct {
-	id         interface{}
-	Identifier string
+	Identifier *string
+	Options    *NameEncodingOptions
 }
 
-// Enforce that NameListCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameListCmd{}
-
-// NewNameListCmd creates a new NameListCmd.
-func NewNameListCmd(id interface{}, optArgs ...interface{}) (*NameListCmd, error) {
-	var identifier string
-	if len(optArgs) > 1 {
-		return nil, btcjson.ErrWrongNumberOfParams
-	}
-	if len(optArgs) > 0 {
-		a, ok := optArgs[0].(string)
-		if !ok {
-			return nil, errors.New("optional argument identifier is not a string")
-		}
-		identifier = a
+// NewNameListCmd returns a new instance which can be used to issue a
+// name_list JSON-RPC command.  An empty identifier lists all names.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.  namecoind rejects a
+// null argument, so a nil identifier followed by options is sent empty.
+func NewNameListCmd(identifier *string, options *NameEncodingOptions) *NameListCmd {
+	if options != nil && identifier == nil {
+		identifier = btcjson.String("")
 	}
 	return &NameListCmd{
-		id:         id,
 		Identifier: identifier,
-	}, nil
-}
-
-// NameListFromRaw is a RawCmdParser.
-func NameListFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var nameStr string
-	params := make([]interface{}, 0, 1)
-	if len(rawCmd.Params) > 0 {
-		err := json.Unmarshal(rawCmd.Params[0], &nameStr)
-		if err != nil {
-			return NameListCmd{}, err
-		}
-		params = append(params, nameStr)
-	}
-	return NewNameListCmd(rawCmd.Id, params)
-}
-
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameListCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameListCmd) Method() string {
-	return "name_list"
-}
-
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameListCmd) MarshalJSON() ([]byte, error) {
-	params := make([]interface{}, 0, 1)
-	if cmd.Identifier != "" {
-		params = append(params, cmd.Identifier)
-	}
-
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(raw)
-}
-
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameListCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-
-	if len(r.Params) > 1 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	newCmd, err := NameListFromRaw(&r)
-	if err != nil {
-		return err
+		Options:    options,
 	}
-
-	cmd = newCmd.(NameListCmd)
-	return nil
 }
 
-// NameHistoryCmd is a type handling custom marshaling and
-// unmarshaling of name_history JSON RPC commands.
+// NameHistoryCmd defines the name_history JSON-RPC command.
 type NameHistoryCmd struct {
-	id   interface{}
-	Name string
+	Name    string
+	Options *NameEncodingOptions
 }
 
-// Enforce that NameHistoryCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameHistoryCmd{}
-
-// NewNameHistoryCmd creates a new NameHistoryCmd.
-func NewNameHistoryCmd(id interface{}, name string) (*NameHistoryCmd, error) {
+// NewNameHistoryCmd returns a new instance which can be used to issue a
+// name_history JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNameHistoryCmd(name string, options *NameEncodingOptions) *NameHistoryCmd {
 	return &NameHistoryCmd{
-		id:   id,
-		Name: name,
-	}, nil
-}
-
-// NameHistoryFromRaw is a RawCmdParser.
-func NameHistoryFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var nameStr string
-	err := json.Unmarshal(rawCmd.Params[0], &nameStr)
-	if err != nil {
-		return NameHistoryCmd{}, err
-	}
-
-	newCmd, err := NewNameHistoryCmd(rawCmd.Id, nameStr)
-	if err != nil {
-		return *newCmd, err
+		Name:    name,
+		Options: options,
 	}
-	return *newCmd, nil
-}
-
-// Id satisfies the Cmd interface by returning the id of the command.
-func (cmd NameHistoryCmd) Id() interface{} {
-	return cmd.id
-}
-
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameHistoryCmd) Method() string {
-	return "name_history"
 }
 
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameHistoryCmd) MarshalJSON() ([]byte, error) {
-	params := []interface{}{
-		cmd.Name,
-	}
+// defaultNameScanCount is the default count of name_scan, filled in by
+// NewNameScanCmd when options are set.
+const defaultNameScanCount = 500
 
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(raw)
-}
-
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameHistoryCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-
-	if len(r.Params) != 1 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	var name string
-	if err := json.Unmarshal(r.Params[0], &name); err != nil {
-		return fmt.Errorf("name parameter must be a string: %v", err)
-	}
-
-	newCmd, err := NewNameHistoryCmd(r.Id, name)
-	if err != nil {
-		return err
-	}
-	cmd = *newCmd
-	return nil
-}
-
-// NameScanCmd is a type handling custom marshaling and
-// unmarshaling of name_scan JSON RPC commands.
+// NameScanCmd defines the name_scan JSON-RPC command.
 type NameScanCmd struct {
-	id          interface{}
-	StartName   string
-	MaxReturned int
+	StartName   *string `jsonrpcdefault:"\"\""`
+	MaxReturned *int    `jsonrpcdefault:"500"`
+	Options     *NameScanOptions
 }
 
-// Enforce that NameScanCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameScanCmd{}
-
-// NewNameScanCmd creates a new NameScanCmd.
-func NewNameScanCmd(id interface{}, optArgs ...interface{}) (*NameScanCmd, error) {
-	var startName string
-	var maxReturned int
-
-	if len(optArgs) > 2 {
-		return nil, btcjson.ErrWrongNumberOfParams
+// NewNameScanCmd returns a new instance which can be used to issue a
+// name_scan JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.  namecoind rejects a
+// null argument, so the defaults of nil parameters followed by a set one are
+// filled in.
+func NewNameScanCmd(startName *string, maxReturned *int, options *NameScanOptions) *NameScanCmd {
+	if options != nil && maxReturned == nil {
+		maxReturned = btcjson.Int(defaultNameScanCount)
 	}
-	if len(optArgs) > 0 {
-		a, ok := optArgs[0].(string)
-		if !ok {
-			return nil, errors.New("first optional argument start-name is not a string")
-		}
-		startName = a
+	if maxReturned != nil && startName == nil {
+		startName = btcjson.String("")
 	}
-	if len(optArgs) > 1 {
-		b, ok := optArgs[1].(int)
-		if !ok {
-			return nil, errors.New("second optional argument max-returned is not an int")
-		}
-		maxReturned = b
-	}
-
 	return &NameScanCmd{
-		id:          id,
 		StartName:   startName,
 		MaxReturned: maxReturned,
-	}, nil
-}
-
-// NameScanFromRaw is a RawCmdParser.
-func NameScanFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var startName string
-	var maxReturned int
-	params := make([]interface{}, 0, 2)
-
-	if len(rawCmd.Params) > 0 {
-		err := json.Unmarshal(rawCmd.Params[0], &startName)
-		if err != nil {
-			return NameScanCmd{}, err
-		}
-		params = append(params, startName)
-	}
-
-	if len(rawCmd.Params) > 1 {
-		err := json.Unmarshal(rawCmd.Params[1], &maxReturned)
-		if err != nil {
-			return NameScanCmd{}, err
-		}
-		params = append(params, maxReturned)
+		Options:     options,
 	}
-
-	newCmd, err := NewNameScanCmd(rawCmd.Id, params)
-	if err != nil {
-		return NameScanCmd{}, err
-	}
-	return *newCmd, nil
-}
-
-// Id satisfies the Cmd interace by returning the id of the command.
-func (cmd NameScanCmd) Id() interface{} {
-	return cmd.id
 }
 
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameScanCmd) Method() string {
-	return "name_scan"
+// NamePendingCmd defines the name_pending JSON-RPC command.
+type NamePendingCmd struct {
+	Name    *string
+	Options *NameEncodingOptions
 }
 
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameScanCmd) MarshalJSON() ([]byte, error) {
-	params := make([]interface{}, 0, 2)
-	if cmd.StartName != "" {
-		params = append(params, cmd.StartName)
+// NewNamePendingCmd returns a new instance which can be used to issue a
+// name_pending JSON-RPC command.  When name is nil or empty, pending
+// operations on all names are returned.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.  namecoind rejects a
+// null argument, so a nil name followed by options is sent empty.
+func NewNamePendingCmd(name *string, options *NameEncodingOptions) *NamePendingCmd {
+	if options != nil && name == nil {
+		name = btcjson.String("")
 	}
-	if cmd.MaxReturned != 0 {
-		params = append(params, cmd.MaxReturned)
-	}
-
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
-		return nil, err
+	return &NamePendingCmd{
+		Name:    name,
+		Options: options,
 	}
-	return json.Marshal(raw)
 }
 
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameScanCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
-		return err
-	}
-
-	if len(r.Params) > 2 {
-		return btcjson.ErrWrongNumberOfParams
-	}
-
-	newCmd, err := NameScanFromRaw(&r)
-	if err != nil {
-		return err
-	}
-	cmd = newCmd.(NameScanCmd)
-	return nil
-}
+// NameFilterStat is the value of the stat parameter of name_filter which
+// requests statistics instead of results.
+const NameFilterStat = "stat"
 
-// NameFilterCmd is a type handling custom marshaling and
-// unmarshaling of name_filter JSON RPC commands.
+// NameFilterCmd defines the name_filter JSON-RPC command.  MaxAge has no
+// default tag, since its default is the DefaultFilterMaxAge of the network.
 type NameFilterCmd struct {
-	id     interface{}
-	Regexp string
-	MaxAge int
-	From   int
-	Nb     int
-	Stat   int
-}
-
-// Enforce that NameFilterCmd satisfies the Cmd interface.
-var _ btcjson.Cmd = &NameFilterCmd{}
-
-// NewNameFilterCmd creates a new NameFilterCmd.
-func NewNameFilterCmd(id interface{}, optArgs ...interface{}) (*NameFilterCmd, error) {
-	if len(optArgs) > 5 {
-		return nil, btcjson.ErrWrongNumberOfParams
-	}
-	var regexp string
-	var maxage int
-	var from int
-	var nb int
-	var stat int
-	if len(optArgs) > 0 {
-		a, ok := optArgs[0].(string)
-		if !ok {
-			return nil, errors.New("first optional argument regexp is not a string")
-		}
-		regexp = a
-	}
-	if len(optArgs) > 1 {
-		b, ok := optArgs[1].(int)
-		if !ok {
-			return nil, errors.New("second optional argument maxage is not an int")
-		}
-		maxage = b
+	Regexp *string `jsonrpcdefault:"\"\""`
+	MaxAge *int
+	From   *int `jsonrpcdefault:"0"`
+	Nb     *int `jsonrpcdefault:"0"`
+	Stat   *string
+}
+
+// NewNameFilterCmd returns a new instance which can be used to issue a
+// name_filter JSON-RPC command.  Setting stat to NameFilterStat requests
+// statistics instead of results.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.  namecoind rejects a
+// null argument, so the defaults of nil parameters followed by a set one are
+// filled in.  The default of maxAge depends on the network, so it is not
+// filled in and must be set along with from, nb or stat; Client sets it from
+// its Params.
+func NewNameFilterCmd(regexp *string, maxAge, from, nb *int, stat *string) *NameFilterCmd {
+	if stat != nil && nb == nil {
+		nb = btcjson.Int(0)
+	}
+	if nb != nil && from == nil {
+		from = btcjson.Int(0)
+	}
+	if maxAge != nil && regexp == nil {
+		regexp = btcjson.String("")
 	}
-	if len(optArgs) > 2 {
-		c, ok := optArgs[2].(int)
-		if !ok {
-			return nil, errors.New("third optional argument from is not an int")
-		}
-		from = c
-	}
-	if len(optArgs) > 3 {
-		d, ok := optArgs[3].(int)
-		if !ok {
-			return nil, errors.New("fourth optional argument nb is not an int")
-		}
-		nb = d
-	}
-	if len(optArgs) > 4 {
-		e, ok := optArgs[4].(int)
-		if !ok {
-			return nil, errors.New("fifth optional argument stat is not an int")
-		}
-		stat = e
-	}
-
 	return &NameFilterCmd{
-		id:     id,
 		Regexp: regexp,
-		MaxAge: maxage,
+		MaxAge: maxAge,
 		From:   from,
 		Nb:     nb,
 		Stat:   stat,
-	}, nil
-
-}
-
-// NameFilterFromRaw is a RawCmdParser.
-func NameFilterFromRaw(rawCmd *btcjson.RawCmd) (btcjson.Cmd, error) {
-	var regexp string
-	var maxage int
-	var from int
-	var nb int
-	var stat int
-	params := make([]interface{}, 0, 5)
-
-	if len(rawCmd.Params) > 0 {
-		err := json.Unmarshal(rawCmd.Params[0], &regexp)
-		if err != nil {
-			return NameFilterCmd{}, err
-		}
-		params = append(params, regexp)
-	}
-	if len(rawCmd.Params) > 1 {
-		err := json.Unmarshal(rawCmd.Params[1], &maxage)
-		if err != nil {
-			return NameFilterCmd{}, err
-		}
-		params = append(params, maxage)
-	}
-	if len(rawCmd.Params) > 2 {
-		err := json.Unmarshal(rawCmd.Params[2], &from)
-		if err != nil {
-			return NameFilterCmd{}, err
-		}
-		params = append(params, from)
-	}
-	if len(rawCmd.Params) > 3 {
-		err := json.Unmarshal(rawCmd.Params[3], &nb)
-		if err != nil {
-			return NameFilterCmd{}, err
-		}
-		params = append(params, nb)
-	}
-	if len(rawCmd.Params) > 4 {
-		err := json.Unmarshal(rawCmd.Params[4], &stat)
-		if err != nil {
-			return NameFilterCmd{}, err
-		}
-		params = append(params, stat)
-	}
-
-	newCmd, err := NewNameFilterCmd(rawCmd.Id, params)
-	if err != nil {
-		return NameFilterCmd{}, err
 	}
-	return *newCmd, nil
 }
 
-// Id satisfies the Cmd interace by returning the id of the command.
-func (cmd NameFilterCmd) Id() interface{} {
-	return cmd.id
+// IsStat reports whether cmd requests statistics instead of results.
+func (cmd *NameFilterCmd) IsStat() bool {
+	return cmd.Stat != nil && *cmd.Stat == NameFilterStat
 }
 
-// Method satisfies the Cmd interface by returning the json method.
-func (cmd NameFilterCmd) Method() string {
-	return "name_filter"
+// NameOp models the nameop argument of the namerawtransaction command.  Op
+// is one of OpNameNew, OpNameFirstUpdate and OpNameUpdate.  Value is not
+// sent for name_new, and Rand may be left empty for name_new to have the
+// node generate it.
+type NameOp struct {
+	Op    string
+	Name  string
+	Value string
+	Rand  string
 }
 
-// MarshalJSON returns the JSON encoding of cmd. Part of the Cmd interface.
-func (cmd NameFilterCmd) MarshalJSON() ([]byte, error) {
-	params := make([]interface{}, 0, 5)
-	if cmd.Regexp != "" {
-		params = append(params, cmd.Regexp)
+// MarshalJSON encodes o as a nameop object.  The value is omitted for
+// name_new only, so an empty value is kept for the other operations.
+func (o NameOp) MarshalJSON() ([]byte, error) {
+	type nameOp struct {
+		Op    string  `json:"op"`
+		Name  string  `json:"name"`
+		Value *string `json:"value,omitempty"`
+		Rand  string  `json:"rand,omitempty"`
 	}
-	if cmd.MaxAge != 0 {
-		params = append(params, cmd.MaxAge)
-	} else {
-		params = append(params, 36000)
+	op := nameOp{Op: o.Op, Name: o.Name, Rand: o.Rand}
+	if o.Op != OpNameNew {
+		op.Value = &o.Value
 	}
-	params = append(params, cmd.From)
-	params = append(params, cmd.Nb)
-	if cmd.Stat != 0 {
-		params = append(params, cmd.Stat)
-	}
-
-	raw, err := btcjson.NewRawCmd(cmd.id, cmd.Method(), params)
-	if err != nil {
-		return nil, err
-	}
-	return json.Marshal(raw)
+	return json.Marshal(op)
 }
 
-// UnmarshalJSON unmarshals the JSON encoding of cmd into cmd. Part of the Cmd interface.
-func (cmd NameFilterCmd) UnmarshalJSON(b []byte) error {
-	var r btcjson.RawCmd
-	if err := json.Unmarshal(b, &r); err != nil {
+// UnmarshalJSON decodes a nameop object into o.
+func (o *NameOp) UnmarshalJSON(b []byte) error {
+	var op struct {
+		Op    string `json:"op"`
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Rand  string `json:"rand"`
+	}
+	if err := json.Unmarshal(b, &op); err != nil {
 		return err
 	}
+	*o = NameOp(op)
+	return nil
+}
 
-	if len(r.Params) > 5 {
-		return btcjson.ErrWrongNumberOfParams
-	}
+// NameRawTransactionCmd defines the namerawtransaction JSON-RPC command.
+type NameRawTransactionCmd struct {
+	HexTx  string
+	Vout   int
+	NameOp NameOp
+}
 
-	newCmd, err := NameFilterFromRaw(&r)
-	if err != nil {
-		return err
+// NewNameRawTransactionCmd returns a new instance which can be used to issue
+// a namerawtransaction JSON-RPC command, which attaches nameOp to output
+// vout of the raw transaction hexTx.  The command is checked with Validate;
+// to skip the checks, build a NameRawTransactionCmd directly.
+func NewNameRawTransactionCmd(hexTx string, vout int, nameOp NameOp) (*NameRawTransactionCmd, error) {
+	cmd := &NameRawTransactionCmd{
+		HexTx:  hexTx,
+		Vout:   vout,
+		NameOp: nameOp,
 	}
-	cmd = newCmd.(NameFilterCmd)
-	return nil
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
 }
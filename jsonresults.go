@@ -2,6 +2,7 @@ package nmcjson
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 // NameNewResult models the data from the name_new command.
@@ -16,29 +17,54 @@ type NameUpdateResult string
 // NameFirstUpdateResult models the data from the name_firstupdate command.
 type NameFirstUpdateResult string
 
+// NameValue is a name and its value in the encodings of a reply.  It is
+// embedded in the results of the commands which return names.
+type NameValue struct {
+	Name          string   `json:"name"`
+	Value         string   `json:"value"`
+	NameEncoding  Encoding `json:"name_encoding,omitempty"`
+	ValueEncoding Encoding `json:"value_encoding,omitempty"`
+}
+
+// NameBytes returns the raw bytes of the name, decoded according to its
+// NameEncoding.
+func (v *NameValue) NameBytes() ([]byte, error) {
+	return v.NameEncoding.Decode(v.Name)
+}
+
+// ValueBytes returns the raw bytes of the value, decoded according to its
+// ValueEncoding.
+func (v *NameValue) ValueBytes() ([]byte, error) {
+	return v.ValueEncoding.Decode(v.Value)
+}
+
 // NameShowResult models the data from the name_show command.
 type NameShowResult struct {
-	Name      string `json:"name"`
-	Value     string `json:"value"`
+	NameValue
 	Txid      string `json:"txid"`
-	Vout      int    `json:"vout",omitempty`
+	Vout      int    `json:"vout,omitempty"`
 	Address   string `json:"address"`
-	Height    int64  `json:"height",omitempty`
+	Height    int64  `json:"height,omitempty"`
 	ExpiresIn int64  `json:"expires_in"`
-	Expired   bool   `json:"expired",omitempty`
+	Expired   bool   `json:"expired,omitempty"`
+
+	NameError  string `json:"name_error,omitempty"`
+	ValueError string `json:"value_error,omitempty"`
 }
 
 // NameListResult models the data from the name_list command.
 type NameListResult struct {
-	Name        string `json:"name"`
-	Value       string `json:"value"`
-	Txid        string `json:"txid",omitempty`
-	Vout        int    `json:"vout",omitempty`
+	NameValue
+	Txid        string `json:"txid,omitempty"`
+	Vout        int    `json:"vout,omitempty"`
 	Address     string `json:"address"`
-	Height      int64  `json:"height",omitempty`
+	Height      int64  `json:"height,omitempty"`
 	ExpiresIn   int64  `json:"expires_in"`
-	Expired     bool   `json:"expired",omitempty`
-	Transferred bool   `json:"transferred",omitempty"`
+	Expired     bool   `json:"expired,omitempty"`
+	Transferred bool   `json:"transferred,omitempty"`
+
+	NameError  string `json:"name_error,omitempty"`
+	ValueError string `json:"value_error,omitempty"`
 }
 
 // NameHistoryResult models the data from the name_history command.
@@ -46,20 +72,47 @@ type NameHistoryResult NameShowResult
 
 // NameScanResult models the data from the name_scan command.
 type NameScanResult struct {
-	Name      string `json:"name"`
-	Value     string `json:"value"`
-	Txid      string `json:"txid",omitempty`
-	Vout      int    `json:"vout",omitempty`
-	Address   string `json:"address",omitempty`
-	Height    int64  `json:"height",omitempty`
+	NameValue
+	Txid      string `json:"txid,omitempty"`
+	Vout      int    `json:"vout,omitempty"`
+	Address   string `json:"address,omitempty"`
+	Height    int64  `json:"height,omitempty"`
 	ExpiresIn int64  `json:"expires_in"`
-	Expired   bool   `json:"expired",omitempty`
+	Expired   bool   `json:"expired,omitempty"`
+
+	NameError  string `json:"name_error,omitempty"`
+	ValueError string `json:"value_error,omitempty"`
 }
 
 // NameFilterResult models the data from the name_filter command.
 type NameFilterResult NameScanResult
 
-// NameNewReplyParse is a ReplyParser.
+// NameFilterStatResult models the data from the name_filter command when
+// statistics are requested with NameFilterStat.
+type NameFilterStatResult struct {
+	Blocks int64 `json:"blocks"`
+	Count  int   `json:"count"`
+}
+
+// NamePendingResult models the data from the name_pending command.  The
+// value is empty for name_new operations.
+type NamePendingResult struct {
+	NameValue
+	Op      string `json:"op"`
+	Txid    string `json:"txid"`
+	Vout    int    `json:"vout"`
+	Address string `json:"address,omitempty"`
+	IsMine  bool   `json:"ismine"`
+}
+
+// NameRawTransactionResult models the data from the namerawtransaction
+// command.  Rand is only set for name_new operations.
+type NameRawTransactionResult struct {
+	Hex  string `json:"hex"`
+	Rand string `json:"rand,omitempty"`
+}
+
+// NameNewReplyParse parses the result of a name_new reply.
 func NameNewReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res NameNewResult
 	strs := make([]string, 0, 2)
@@ -67,10 +120,9 @@ func NameNewReplyParse(msg json.RawMessage) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	//err := json.Unmarshal(msg, &res)
-	//if err != nil {
-	//	return nil, err
-	//}
+	if len(strs) != 2 {
+		return nil, fmt.Errorf("name_new reply has %d elements, expected 2", len(strs))
+	}
 	res = NameNewResult{
 		Txid: strs[0],
 		Rand: strs[1],
@@ -78,7 +130,7 @@ func NameNewReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameUpdateReplyParse is a ReplyParser.
+// NameUpdateReplyParse parses the result of a name_update reply.
 func NameUpdateReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res NameUpdateResult
 	err := json.Unmarshal(msg, &res)
@@ -88,7 +140,7 @@ func NameUpdateReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameFirstUpdateReplyParse is a ReplyParser.
+// NameFirstUpdateReplyParse parses the result of a name_firstupdate reply.
 func NameFirstUpdateReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res NameFirstUpdateResult
 	err := json.Unmarshal(msg, &res)
@@ -98,7 +150,7 @@ func NameFirstUpdateReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameShowReplyParse is a ReplyParser.
+// NameShowReplyParse parses the result of a name_show reply.
 func NameShowReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res NameShowResult
 	err := json.Unmarshal(msg, &res)
@@ -108,7 +160,7 @@ func NameShowReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameListReplyParse is a ReplyParser.
+// NameListReplyParse parses the result of a name_list reply.
 func NameListReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res []NameListResult
 	err := json.Unmarshal(msg, &res)
@@ -118,7 +170,7 @@ func NameListReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameHistoryReplyParse is a ReplyParser.
+// NameHistoryReplyParse parses the result of a name_history reply.
 func NameHistoryReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res []NameHistoryResult
 	err := json.Unmarshal(msg, &res)
@@ -128,7 +180,7 @@ func NameHistoryReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameScanReplyParse is a ReplyParser.
+// NameScanReplyParse parses the result of a name_scan reply.
 func NameScanReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res []NameScanResult
 	err := json.Unmarshal(msg, &res)
@@ -138,7 +190,7 @@ func NameScanReplyParse(msg json.RawMessage) (interface{}, error) {
 	return res, nil
 }
 
-// NameFilterReplyParse is a ReplyParser.
+// NameFilterReplyParse parses the result of a name_filter reply.
 func NameFilterReplyParse(msg json.RawMessage) (interface{}, error) {
 	var res []NameFilterResult
 	err := json.Unmarshal(msg, &res)
@@ -147,3 +199,45 @@ func NameFilterReplyParse(msg json.RawMessage) (interface{}, error) {
 	}
 	return res, nil
 }
+
+// NameFilterStatReplyParse parses the result of a name_filter reply when
+// statistics were requested.
+func NameFilterStatReplyParse(msg json.RawMessage) (interface{}, error) {
+	var res NameFilterStatResult
+	err := json.Unmarshal(msg, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ParseReply parses the result of a reply to cmd, with
+// NameFilterStatReplyParse when cmd requests statistics and with
+// NameFilterReplyParse otherwise.
+func (cmd *NameFilterCmd) ParseReply(msg json.RawMessage) (interface{}, error) {
+	if cmd.IsStat() {
+		return NameFilterStatReplyParse(msg)
+	}
+	return NameFilterReplyParse(msg)
+}
+
+// NamePendingReplyParse parses the result of a name_pending reply.
+func NamePendingReplyParse(msg json.RawMessage) (interface{}, error) {
+	var res []NamePendingResult
+	err := json.Unmarshal(msg, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// NameRawTransactionReplyParse parses the result of a namerawtransaction
+// reply.
+func NameRawTransactionReplyParse(msg json.RawMessage) (interface{}, error) {
+	var res NameRawTransactionResult
+	err := json.Unmarshal(msg, &res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,18 @@
+/*
+Package namescript decodes and builds the output scripts of Namecoin name
+operations.
+
+A name output script starts with a name operation and its arguments,
+followed by drops which leave the stack as it was, followed by an ordinary
+address script:
+
+	OP_NAME_NEW <hash> OP_2DROP <address script>
+	OP_NAME_FIRSTUPDATE <name> <rand> <value> OP_2DROP OP_2DROP <address script>
+	OP_NAME_UPDATE <name> <value> OP_2DROP OP_DROP <address script>
+
+Decode parses such a script into a Script, following the rules of Namecoin
+Core, and Script.Encode builds one.  Scripts of name_firstupdate and
+name_update operations convert to and from nmcjson.NameShowResult, so name
+outputs found in raw transactions can be handled like name_show replies.
+*/
+package namescript
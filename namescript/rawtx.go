@@ -0,0 +1,99 @@
+package namescript
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/kefkius/nmcjson"
+)
+
+// NamecoinTxVersion is the version of transactions with name operations.
+const NamecoinTxVersion = 0x7100
+
+// AttachNameOp attaches op to output vout of tx as the namerawtransaction
+// RPC does: the name script is prepended to the script of the output and tx
+// is given NamecoinTxVersion.  For name_new, a rand is generated when op.Rand
+// is empty.  The hex encoded rand is returned for name_new and is empty
+// otherwise.
+func AttachNameOp(tx *wire.MsgTx, vout int, op nmcjson.NameOp) (string, error) {
+	if err := op.Validate(); err != nil {
+		return "", err
+	}
+	if vout < 0 || vout >= len(tx.TxOut) {
+		return "", fmt.Errorf("invalid vout %d: the transaction has %d outputs", vout, len(tx.TxOut))
+	}
+
+	out := tx.TxOut[vout]
+	var s *Script
+	var rand string
+	switch op.Op {
+	case nmcjson.OpNameNew:
+		rand = op.Rand
+		if rand == "" {
+			var err error
+			if rand, err = nmcjson.NewRand(); err != nil {
+				return "", err
+			}
+		}
+		hash, err := nmcjson.NameCommitment(op.Name, rand)
+		if err != nil {
+			return "", err
+		}
+		s = NewNameNew(hash, out.PkScript)
+	case nmcjson.OpNameFirstUpdate:
+		r, _ := hex.DecodeString(op.Rand)
+		s = NewNameFirstUpdate([]byte(op.Name), r, []byte(op.Value), out.PkScript)
+	case nmcjson.OpNameUpdate:
+		s = NewNameUpdate([]byte(op.Name), []byte(op.Value), out.PkScript)
+	}
+	script, err := s.Encode()
+	if err != nil {
+		return "", err
+	}
+	out.PkScript = script
+	tx.Version = NamecoinTxVersion
+	return rand, nil
+}
+
+// NameRawTransaction is the offline equivalent of the namerawtransaction RPC.
+// It returns the same unsigned transaction and rand as the node, except that
+// a generated name_new rand differs, so results can be cross-checked.
+func NameRawTransaction(hexTx string, vout int, op nmcjson.NameOp) (*nmcjson.NameRawTransactionResult, error) {
+	tx, err := decodeTx(hexTx)
+	if err != nil {
+		return nil, err
+	}
+	rand, err := AttachNameOp(tx, vout, op)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return &nmcjson.NameRawTransactionResult{
+		Hex:  hex.EncodeToString(buf.Bytes()),
+		Rand: rand,
+	}, nil
+}
+
+// decodeTx decodes the hex encoded transaction hexTx.  Like namecoind, a
+// transaction which fails to decode with witness data is decoded again
+// without, since a transaction without inputs looks like a witness marker.
+func decodeTx(hexTx string) (*wire.MsgTx, error) {
+	b, err := hex.DecodeString(hexTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(bytes.NewReader(b)); err == nil {
+		return tx, nil
+	}
+	tx = new(wire.MsgTx)
+	if err := tx.DeserializeNoWitness(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,333 @@
+package namescript
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// Op is the opcode of a name operation.  Name operations reuse the opcodes
+// of OP_1, OP_2 and OP_3.
+type Op byte
+
+// Name operation opcodes.
+const (
+	OpNameNew         Op = 0x51
+	OpNameFirstUpdate Op = 0x52
+	OpNameUpdate      Op = 0x53
+)
+
+// String returns the RPC name of o, such as "name_update".
+func (o Op) String() string {
+	switch o {
+	case OpNameNew:
+		return nmcjson.OpNameNew
+	case OpNameFirstUpdate:
+		return nmcjson.OpNameFirstUpdate
+	case OpNameUpdate:
+		return nmcjson.OpNameUpdate
+	}
+	return fmt.Sprintf("unknown name op (%#02x)", byte(o))
+}
+
+// Opcodes used by name scripts.
+const (
+	opPushData1 = 0x4c
+	opPushData2 = 0x4d
+	opPushData4 = 0x4e
+	opNop       = 0x61
+	opDrop      = 0x75
+	op2Drop     = 0x6d
+)
+
+// hashLength is the length of the commitment of a name_new.
+const hashLength = 20
+
+var (
+	// ErrNotNameScript indicates a script which is not a name operation.
+	ErrNotNameScript = errors.New("not a name script")
+
+	// ErrNoName indicates a name_new script, which does not reveal its
+	// name, where a name is needed.
+	ErrNoName = errors.New("name_new scripts do not reveal the name")
+)
+
+// Script is a decoded name output script.  Only the fields used by Op are
+// set.
+type Script struct {
+	Op Op
+
+	// Hash is the Hash160(rand || name) commitment of a name_new.
+	Hash []byte
+
+	// Name, Rand and Value are the raw arguments of a name_firstupdate,
+	// and Name and Value those of a name_update.
+	Name  []byte
+	Rand  []byte
+	Value []byte
+
+	// AddressScript is the script following the name operation, which
+	// determines the owner of the name.
+	AddressScript []byte
+}
+
+// NewNameNew returns the script of a name_new committing to hash and paying
+// to addressScript.
+func NewNameNew(hash, addressScript []byte) *Script {
+	return &Script{Op: OpNameNew, Hash: hash, AddressScript: addressScript}
+}
+
+// NewNameFirstUpdate returns the script of a name_firstupdate registering
+// name with value and sending it to addressScript.
+func NewNameFirstUpdate(name, rand, value, addressScript []byte) *Script {
+	return &Script{
+		Op:            OpNameFirstUpdate,
+		Name:          name,
+		Rand:          rand,
+		Value:         value,
+		AddressScript: addressScript,
+	}
+}
+
+// NewNameUpdate returns the script of a name_update setting the value of
+// name and sending it to addressScript.
+func NewNameUpdate(name, value, addressScript []byte) *Script {
+	return &Script{
+		Op:            OpNameUpdate,
+		Name:          name,
+		Value:         value,
+		AddressScript: addressScript,
+	}
+}
+
+// IsNameScript reports whether script starts with a name operation.
+func IsNameScript(script []byte) bool {
+	_, err := Decode(script)
+	return err == nil
+}
+
+// Decode parses script as a name operation.  ErrNotNameScript is returned,
+// wrapped, when script is not one.
+func Decode(script []byte) (*Script, error) {
+	if len(script) == 0 {
+		return nil, ErrNotNameScript
+	}
+	op := Op(script[0])
+	var nargs int
+	switch op {
+	case OpNameNew:
+		nargs = 1
+	case OpNameFirstUpdate:
+		nargs = 3
+	case OpNameUpdate:
+		nargs = 2
+	default:
+		return nil, ErrNotNameScript
+	}
+
+	// Read pushes until the first drop, as Namecoin Core does.
+	var args [][]byte
+	pos := 1
+	for {
+		if pos >= len(script) {
+			return nil, fmt.Errorf("%w: %v has no address script", ErrNotNameScript, op)
+		}
+		c := script[pos]
+		if c == opDrop || c == op2Drop || c == opNop {
+			break
+		}
+		if c > opPushData4 {
+			return nil, fmt.Errorf("%w: non-push opcode %#02x in %v arguments",
+				ErrNotNameScript, c, op)
+		}
+		data, next, err := readPush(script, pos)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotNameScript, err)
+		}
+		args = append(args, data)
+		pos = next
+	}
+	for pos < len(script) && (script[pos] == opDrop || script[pos] == op2Drop || script[pos] == opNop) {
+		pos++
+	}
+	if len(args) != nargs {
+		return nil, fmt.Errorf("%w: %v with %d arguments", ErrNotNameScript, op, len(args))
+	}
+
+	s := &Script{Op: op, AddressScript: script[pos:]}
+	switch op {
+	case OpNameNew:
+		s.Hash = args[0]
+	case OpNameFirstUpdate:
+		s.Name, s.Rand, s.Value = args[0], args[1], args[2]
+	case OpNameUpdate:
+		s.Name, s.Value = args[0], args[1]
+	}
+	return s, nil
+}
+
+// readPush reads the data pushed by the opcode at pos of script and returns
+// it with the position of the next opcode.
+func readPush(script []byte, pos int) ([]byte, int, error) {
+	c := script[pos]
+	pos++
+	var n int
+	switch {
+	case c < opPushData1:
+		n = int(c)
+	case c == opPushData1:
+		if pos+1 > len(script) {
+			return nil, 0, errors.New("truncated OP_PUSHDATA1")
+		}
+		n = int(script[pos])
+		pos++
+	case c == opPushData2:
+		if pos+2 > len(script) {
+			return nil, 0, errors.New("truncated OP_PUSHDATA2")
+		}
+		n = int(binary.LittleEndian.Uint16(script[pos:]))
+		pos += 2
+	case c == opPushData4:
+		if pos+4 > len(script) {
+			return nil, 0, errors.New("truncated OP_PUSHDATA4")
+		}
+		n = int(binary.LittleEndian.Uint32(script[pos:]))
+		pos += 4
+	}
+	if n < 0 || pos+n > len(script) {
+		return nil, 0, errors.New("push past the end of the script")
+	}
+	return script[pos : pos+n], pos + n, nil
+}
+
+// appendPush appends a push of data to script, encoded like Namecoin Core.
+// Unlike txscript.ScriptBuilder, single bytes are never replaced by small
+// integer opcodes, since those are not accepted as name arguments.
+func appendPush(script, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n < opPushData1:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, opPushData1, byte(n))
+	case n <= 0xffff:
+		script = append(script, opPushData2, 0, 0)
+		binary.LittleEndian.PutUint16(script[len(script)-2:], uint16(n))
+	default:
+		script = append(script, opPushData4, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(script[len(script)-4:], uint32(n))
+	}
+	return append(script, data...)
+}
+
+// Validate checks the arguments of s against the consensus limits.
+func (s *Script) Validate() error {
+	switch s.Op {
+	case OpNameNew:
+		if len(s.Hash) != hashLength {
+			return fmt.Errorf("name_new hash is %d bytes, expected %d", len(s.Hash), hashLength)
+		}
+		return nil
+	case OpNameFirstUpdate:
+		if len(s.Rand) > nmcjson.MaxRandLength {
+			return fmt.Errorf("rand is %d bytes, the maximum is %d", len(s.Rand), nmcjson.MaxRandLength)
+		}
+	case OpNameUpdate:
+	default:
+		return fmt.Errorf("unknown name op %#02x", byte(s.Op))
+	}
+	if len(s.Name) > nmcjson.MaxNameLength {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d",
+			nmcjson.ErrNameTooLong, len(s.Name), nmcjson.MaxNameLength)
+	}
+	if len(s.Value) > nmcjson.MaxValueLength {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d",
+			nmcjson.ErrValueTooLong, len(s.Value), nmcjson.MaxValueLength)
+	}
+	return nil
+}
+
+// Encode validates s and returns it as an output script.
+func (s *Script) Encode() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	script := []byte{byte(s.Op)}
+	switch s.Op {
+	case OpNameNew:
+		script = appendPush(script, s.Hash)
+		script = append(script, op2Drop)
+	case OpNameFirstUpdate:
+		script = appendPush(script, s.Name)
+		script = appendPush(script, s.Rand)
+		script = appendPush(script, s.Value)
+		script = append(script, op2Drop, op2Drop)
+	case OpNameUpdate:
+		script = appendPush(script, s.Name)
+		script = appendPush(script, s.Value)
+		script = append(script, op2Drop, opDrop)
+	}
+	return append(script, s.AddressScript...), nil
+}
+
+// NameShowResult returns the name operation of s in the shape of a name_show
+// result for the output txid:vout, with the name and value encoded with enc.
+// Height, ExpiresIn and Address are left for the caller to fill in, since
+// they depend on the chain and the network.  ErrNoName is returned for
+// name_new scripts.
+func (s *Script) NameShowResult(txid string, vout int, enc nmcjson.NameEncodingOptions) (*nmcjson.NameShowResult, error) {
+	if s.Op == OpNameNew {
+		return nil, ErrNoName
+	}
+	name, err := enc.NameEncoding.Encode(s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("name: %v", err)
+	}
+	value, err := enc.ValueEncoding.Encode(s.Value)
+	if err != nil {
+		return nil, fmt.Errorf("value: %v", err)
+	}
+	return &nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{
+			Name:          name,
+			Value:         value,
+			NameEncoding:  enc.NameEncoding,
+			ValueEncoding: enc.ValueEncoding,
+		},
+		Txid: txid,
+		Vout: vout,
+	}, nil
+}
+
+// FromNameShow returns the name_update script which keeps the name and value
+// of r and sends the name to addressScript.
+func FromNameShow(r *nmcjson.NameShowResult, addressScript []byte) (*Script, error) {
+	name, err := r.NameBytes()
+	if err != nil {
+		return nil, fmt.Errorf("name: %v", err)
+	}
+	value, err := r.ValueBytes()
+	if err != nil {
+		return nil, fmt.Errorf("value: %v", err)
+	}
+	return NewNameUpdate(name, value, addressScript), nil
+}
+
+// String returns a disassembly of the name operation of s, followed by its
+// address script in hex.
+func (s *Script) String() string {
+	switch s.Op {
+	case OpNameNew:
+		return fmt.Sprintf("OP_NAME_NEW %x OP_2DROP %s", s.Hash, hex.EncodeToString(s.AddressScript))
+	case OpNameFirstUpdate:
+		return fmt.Sprintf("OP_NAME_FIRSTUPDATE %x %x %x OP_2DROP OP_2DROP %s",
+			s.Name, s.Rand, s.Value, hex.EncodeToString(s.AddressScript))
+	case OpNameUpdate:
+		return fmt.Sprintf("OP_NAME_UPDATE %x %x OP_2DROP OP_DROP %s",
+			s.Name, s.Value, hex.EncodeToString(s.AddressScript))
+	}
+	return s.Op.String()
+}
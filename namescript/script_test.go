@@ -0,0 +1,246 @@
+package namescript
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/kefkius/nmcjson"
+)
+
+// Scripts assembled by hand in the layout of Namecoin Core, paying to the
+// P2PKH script of the hash 000102...13.
+const (
+	p2pkh    = "76a914000102030405060708090a0b0c0d0e0f1011121388ac"
+	nameHex  = "642f6578616d706c65" // d/example
+	randHex  = "0011223344556677889900112233445566778899"
+	hashHex  = "d042d04aff0761fccfdecd09d8193b4f7ceaacb2" // Hash160(rand || name)
+	valueHex = "7b7d"                                     // {}
+
+	newScript         = "51" + "14" + hashHex + "6d" + p2pkh
+	firstUpdateScript = "52" + "09" + nameHex + "14" + randHex + "02" + valueHex + "6d6d" + p2pkh
+	updateScript      = "53" + "09" + nameHex + "02" + valueHex + "6d75" + p2pkh
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeEncode(t *testing.T) {
+	long := strings.Repeat("61", 80)
+	longer := strings.Repeat("62", 300)
+	tests := []struct {
+		name   string
+		script string
+		op     Op
+
+		// The arguments of the operation, hex encoded.
+		hashHex, nameHex, randHex, valueHex string
+
+		asm string
+	}{{
+		name:    "name_new",
+		script:  newScript,
+		op:      OpNameNew,
+		hashHex: hashHex,
+		asm:     "OP_NAME_NEW " + hashHex + " OP_2DROP " + p2pkh,
+	}, {
+		name:     "name_firstupdate",
+		script:   firstUpdateScript,
+		op:       OpNameFirstUpdate,
+		nameHex:  nameHex,
+		randHex:  randHex,
+		valueHex: valueHex,
+		asm:      "OP_NAME_FIRSTUPDATE " + nameHex + " " + randHex + " " + valueHex + " OP_2DROP OP_2DROP " + p2pkh,
+	}, {
+		name:     "name_update",
+		script:   updateScript,
+		op:       OpNameUpdate,
+		nameHex:  nameHex,
+		valueHex: valueHex,
+		asm:      "OP_NAME_UPDATE " + nameHex + " " + valueHex + " OP_2DROP OP_DROP " + p2pkh,
+	}, {
+		name:    "empty value",
+		script:  "53" + "09" + nameHex + "00" + "6d75" + p2pkh,
+		op:      OpNameUpdate,
+		nameHex: nameHex,
+	}, {
+		name:     "OP_PUSHDATA1 value",
+		script:   "53" + "09" + nameHex + "4c50" + long + "6d75" + p2pkh,
+		op:       OpNameUpdate,
+		nameHex:  nameHex,
+		valueHex: long,
+	}, {
+		name:     "OP_PUSHDATA2 value",
+		script:   "53" + "09" + nameHex + "4d2c01" + longer + "6d75" + p2pkh,
+		op:       OpNameUpdate,
+		nameHex:  nameHex,
+		valueHex: longer,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := mustDecodeHex(t, test.script)
+			s, err := Decode(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Op != test.op {
+				t.Fatalf("op: got %v, want %v", s.Op, test.op)
+			}
+			for _, f := range []struct {
+				field string
+				got   []byte
+				want  string
+			}{
+				{"hash", s.Hash, test.hashHex},
+				{"name", s.Name, test.nameHex},
+				{"rand", s.Rand, test.randHex},
+				{"value", s.Value, test.valueHex},
+			} {
+				if got := hex.EncodeToString(f.got); got != f.want {
+					t.Fatalf("%s: got %s, want %s", f.field, got, f.want)
+				}
+			}
+			if got := hex.EncodeToString(s.AddressScript); got != p2pkh {
+				t.Fatalf("address script: got %s, want %s", got, p2pkh)
+			}
+			if test.asm != "" && s.String() != test.asm {
+				t.Fatalf("String: got %s, want %s", s, test.asm)
+			}
+			if !IsNameScript(script) {
+				t.Fatal("IsNameScript: got false")
+			}
+
+			encoded, err := s.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(encoded, script) {
+				t.Fatalf("Encode: got %x, want %s", encoded, test.script)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"empty", ""},
+		{"P2PKH", p2pkh},
+		{"unknown op", "54" + "09" + nameHex + "6d" + p2pkh},
+		{"name_update with three arguments", "53" + "09" + nameHex + "02" + valueHex + "02" + valueHex + "6d6d" + p2pkh},
+		{"name_firstupdate with two arguments", "52" + "09" + nameHex + "02" + valueHex + "6d75" + p2pkh},
+		{"name_new without arguments", "51" + "6d" + p2pkh},
+		{"non-push argument", "53" + "09" + nameHex + "76" + "6d75" + p2pkh},
+		{"truncated push", "53" + "09" + nameHex + "4c"},
+		{"push past the end", "53" + "09" + nameHex + "05" + valueHex},
+		{"no drop", "53" + "09" + nameHex + "02" + valueHex},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script := mustDecodeHex(t, test.script)
+			if _, err := Decode(script); !errors.Is(err, ErrNotNameScript) {
+				t.Fatalf("got %v, want %v", err, ErrNotNameScript)
+			}
+			if IsNameScript(script) {
+				t.Fatal("IsNameScript: got true")
+			}
+		})
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	addr := mustDecodeHex(t, p2pkh)
+	tests := []struct {
+		name   string
+		script *Script
+		err    error
+	}{
+		{"short hash", NewNameNew(make([]byte, 19), addr), nil},
+		{"long rand", NewNameFirstUpdate([]byte("d/x"), make([]byte, nmcjson.MaxRandLength+1), nil, addr), nil},
+		{"long name", NewNameUpdate(make([]byte, nmcjson.MaxNameLength+1), nil, addr), nmcjson.ErrNameTooLong},
+		{"long value", NewNameUpdate([]byte("d/x"), make([]byte, nmcjson.MaxValueLength+1), addr), nmcjson.ErrValueTooLong},
+		{"unknown op", &Script{Op: 0x54, AddressScript: addr}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.script.Encode()
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestNameRawTransaction(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000000, mustDecodeHex(t, p2pkh)))
+	tx.AddTxOut(wire.NewTxOut(5000000, mustDecodeHex(t, p2pkh)))
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	hexTx := hex.EncodeToString(buf.Bytes())
+
+	tests := []struct {
+		name   string
+		op     nmcjson.NameOp
+		script string
+		rand   string
+	}{{
+		name:   "name_new",
+		op:     nmcjson.NameOp{Op: nmcjson.OpNameNew, Name: "d/example", Rand: randHex},
+		script: newScript,
+		rand:   randHex,
+	}, {
+		name:   "name_firstupdate",
+		op:     nmcjson.NameOp{Op: nmcjson.OpNameFirstUpdate, Name: "d/example", Rand: randHex, Value: "{}"},
+		script: firstUpdateScript,
+	}, {
+		name:   "name_update",
+		op:     nmcjson.NameOp{Op: nmcjson.OpNameUpdate, Name: "d/example", Value: "{}"},
+		script: updateScript,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := NameRawTransaction(hexTx, 0, test.op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Rand != test.rand {
+				t.Fatalf("rand: got %q, want %q", result.Rand, test.rand)
+			}
+			got := new(wire.MsgTx)
+			if err := got.Deserialize(bytes.NewReader(mustDecodeHex(t, result.Hex))); err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != NamecoinTxVersion {
+				t.Fatalf("version: got %#x, want %#x", got.Version, NamecoinTxVersion)
+			}
+			if s := hex.EncodeToString(got.TxOut[0].PkScript); s != test.script {
+				t.Fatalf("output 0: got %s, want %s", s, test.script)
+			}
+			if s := hex.EncodeToString(got.TxOut[1].PkScript); s != p2pkh {
+				t.Fatalf("output 1 changed to %s", s)
+			}
+		})
+	}
+
+	if _, err := NameRawTransaction(hexTx, 2, tests[2].op); err == nil {
+		t.Fatal("vout 2: got no error")
+	}
+}
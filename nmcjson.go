@@ -1,17 +1,29 @@
 package nmcjson
 
 import (
+	"sync"
+
 	"github.com/btcsuite/btcd/btcjson"
 )
 
-// Init registers the NMC-specific commands with btcjson.
+var initOnce sync.Once
+
+// Init registers the NMC-specific commands with btcjson.  It is safe to call
+// Init more than once.
 func Init() {
-	btcjson.RegisterCustomCmd("name_new", NameNewFromRaw, NameNewReplyParse, nmcHelpStrings["name_new"])
-	btcjson.RegisterCustomCmd("name_update", NameUpdateFromRaw, NameUpdateReplyParse, nmcHelpStrings["name_update"])
-	btcjson.RegisterCustomCmd("name_firstupdate", NameFirstUpdateFromRaw, NameFirstUpdateReplyParse, nmcHelpStrings["name_firstupdate"])
-	btcjson.RegisterCustomCmd("name_list", NameListFromRaw, NameListReplyParse, nmcHelpStrings["name_list"])
-	btcjson.RegisterCustomCmd("name_history", NameHistoryFromRaw, NameHistoryReplyParse, nmcHelpStrings["name_history"])
-	btcjson.RegisterCustomCmd("name_show", NameShowFromRaw, NameShowReplyParse, nmcHelpStrings["name_show"])
-	btcjson.RegisterCustomCmd("name_scan", NameScanFromRaw, NameScanReplyParse, nmcHelpStrings["name_scan"])
-	btcjson.RegisterCustomCmd("name_filter", NameFilterFromRaw, NameFilterReplyParse, nmcHelpStrings["name_filter"])
+	initOnce.Do(func() {
+		// No special flags for name commands.
+		flags := btcjson.UsageFlag(0)
+
+		btcjson.MustRegisterCmd("name_new", (*NameNewCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_update", (*NameUpdateCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_firstupdate", (*NameFirstUpdateCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_list", (*NameListCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_history", (*NameHistoryCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_show", (*NameShowCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_scan", (*NameScanCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_filter", (*NameFilterCmd)(nil), flags)
+		btcjson.MustRegisterCmd("name_pending", (*NamePendingCmd)(nil), flags)
+		btcjson.MustRegisterCmd("namerawtransaction", (*NameRawTransactionCmd)(nil), flags)
+	})
 }
@@ -0,0 +1,8 @@
+/*
+Package nmcrpcclient adds the nmcjson name commands to btcd's rpcclient.
+
+Each command has a blocking method, such as NameShow, and an asynchronous
+variant, such as NameShowAsync, which returns a future whose Receive method
+waits for the reply and parses it into the matching nmcjson result type.
+*/
+package nmcrpcclient
@@ -0,0 +1,485 @@
+package nmcrpcclient
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/kefkius/nmcjson"
+)
+
+// Client wraps an rpcclient.Client with the Namecoin name commands.  All of
+// the rpcclient.Client methods remain available, and name commands share its
+// connection, batching and notification handling.
+type Client struct {
+	*rpcclient.Client
+	params *nmcjson.Params
+}
+
+// New returns a Client which sends name commands through client to a node of
+// the network of params.  A nil params means nmcjson.MainNetParams.  The
+// addresses of transaction commands are checked against that network before
+// they are sent.
+func New(client *rpcclient.Client, params *nmcjson.Params) *Client {
+	if params == nil {
+		params = &nmcjson.MainNetParams
+	}
+	nmcjson.Init()
+	return &Client{Client: client, params: params}
+}
+
+// futureRaw is a raw rpcclient future, or the error which prevented the
+// request from being sent.
+type futureRaw struct {
+	raw rpcclient.FutureRawResult
+	err error
+}
+
+// receive waits for the response promised by the future and returns the raw
+// result.  Server errors are returned as *nmcjson.RPCError.
+func (r futureRaw) receive() (json.RawMessage, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	res, err := r.raw.Receive()
+	if err != nil {
+		return nil, nmcjson.WrapRPCError(err)
+	}
+	return res, nil
+}
+
+// sendCmd sends the registered command cmd asynchronously.  The fields of
+// cmd are its positional parameters, and trailing nil optional parameters are
+// omitted, as btcjson.MarshalCmd does.  The request id is assigned by
+// RawRequestAsync.
+func (c *Client) sendCmd(cmd interface{}) futureRaw {
+	method, err := btcjson.CmdMethod(cmd)
+	if err != nil {
+		return futureRaw{err: err}
+	}
+	rv := reflect.ValueOf(cmd).Elem()
+	params := make([]json.RawMessage, 0, rv.NumField())
+	n := 0
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		param, err := json.Marshal(field.Interface())
+		if err != nil {
+			return futureRaw{err: err}
+		}
+		params = append(params, param)
+		if field.Kind() != reflect.Ptr || !field.IsNil() {
+			n = len(params)
+		}
+	}
+	return futureRaw{raw: c.RawRequestAsync(method, params[:n])}
+}
+
+// FutureNameNewResult is a future promise to deliver the result of a
+// NameNewAsync RPC invocation (or an applicable error).
+type FutureNameNewResult futureRaw
+
+// Receive waits for the response promised by the future and returns the txid
+// and rand of the name_new transaction.
+func (r FutureNameNewResult) Receive() (*nmcjson.NameNewResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameNewReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	result := parsed.(nmcjson.NameNewResult)
+	return &result, nil
+}
+
+// NameNewAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameNew for the blocking version and more details.
+func (c *Client) NameNewAsync(name string, options *nmcjson.NameTxOptions) FutureNameNewResult {
+	cmd, err := nmcjson.NewNameNewCmd(name, options)
+	if err == nil {
+		err = cmd.Validate(c.params)
+	}
+	if err != nil {
+		return FutureNameNewResult{err: err}
+	}
+	return FutureNameNewResult(c.sendCmd(cmd))
+}
+
+// NameNew pre-orders name and returns the txid and rand needed to register it
+// with NameFirstUpdate.
+func (c *Client) NameNew(name string, options *nmcjson.NameTxOptions) (*nmcjson.NameNewResult, error) {
+	return c.NameNewAsync(name, options).Receive()
+}
+
+// FutureNameFirstUpdateResult is a future promise to deliver the result of a
+// NameFirstUpdateAsync RPC invocation (or an applicable error).
+type FutureNameFirstUpdateResult futureRaw
+
+// Receive waits for the response promised by the future and returns the txid
+// of the name_firstupdate transaction.
+func (r FutureNameFirstUpdateResult) Receive() (nmcjson.NameFirstUpdateResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return "", err
+	}
+	parsed, err := nmcjson.NameFirstUpdateReplyParse(res)
+	if err != nil {
+		return "", err
+	}
+	return parsed.(nmcjson.NameFirstUpdateResult), nil
+}
+
+// NameFirstUpdateAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See NameFirstUpdate for the blocking version and more details.
+func (c *Client) NameFirstUpdateAsync(name, rand, txid, value string, options *nmcjson.NameTxOptions) FutureNameFirstUpdateResult {
+	cmd, err := nmcjson.NewNameFirstUpdateCmd(name, rand, txid, value, options)
+	if err == nil {
+		err = cmd.Validate(c.params)
+	}
+	if err != nil {
+		return FutureNameFirstUpdateResult{err: err}
+	}
+	return FutureNameFirstUpdateResult(c.sendCmd(cmd))
+}
+
+// NameFirstUpdate registers a name reserved by the name_new transaction txid.
+func (c *Client) NameFirstUpdate(name, rand, txid, value string, options *nmcjson.NameTxOptions) (nmcjson.NameFirstUpdateResult, error) {
+	return c.NameFirstUpdateAsync(name, rand, txid, value, options).Receive()
+}
+
+// FutureNameUpdateResult is a future promise to deliver the result of a
+// NameUpdateAsync RPC invocation (or an applicable error).
+type FutureNameUpdateResult futureRaw
+
+// Receive waits for the response promised by the future and returns the txid
+// of the name_update transaction.
+func (r FutureNameUpdateResult) Receive() (nmcjson.NameUpdateResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return "", err
+	}
+	parsed, err := nmcjson.NameUpdateReplyParse(res)
+	if err != nil {
+		return "", err
+	}
+	return parsed.(nmcjson.NameUpdateResult), nil
+}
+
+// NameUpdateAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameUpdate for the blocking version and more details.
+func (c *Client) NameUpdateAsync(name, value string, options *nmcjson.NameTxOptions) FutureNameUpdateResult {
+	cmd, err := nmcjson.NewNameUpdateCmd(name, value, options)
+	if err == nil {
+		err = cmd.Validate(c.params)
+	}
+	if err != nil {
+		return FutureNameUpdateResult{err: err}
+	}
+	return FutureNameUpdateResult(c.sendCmd(cmd))
+}
+
+// NameUpdate updates the value of name and transfers it when
+// options.DestAddress is set.
+func (c *Client) NameUpdate(name, value string, options *nmcjson.NameTxOptions) (nmcjson.NameUpdateResult, error) {
+	return c.NameUpdateAsync(name, value, options).Receive()
+}
+
+// FutureNameShowResult is a future promise to deliver the result of a
+// NameShowAsync RPC invocation (or an applicable error).
+type FutureNameShowResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// current state of the name.
+func (r FutureNameShowResult) Receive() (*nmcjson.NameShowResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameShowReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	result := parsed.(nmcjson.NameShowResult)
+	return &result, nil
+}
+
+// NameShowAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameShow for the blocking version and more details.
+func (c *Client) NameShowAsync(name string, options *nmcjson.NameShowOptions) FutureNameShowResult {
+	cmd := nmcjson.NewNameShowCmd(name, options)
+	return FutureNameShowResult(c.sendCmd(cmd))
+}
+
+// NameShow returns the current state of name.
+func (c *Client) NameShow(name string, options *nmcjson.NameShowOptions) (*nmcjson.NameShowResult, error) {
+	return c.NameShowAsync(name, options).Receive()
+}
+
+// FutureNameHistoryResult is a future promise to deliver the result of a
+// NameHistoryAsync RPC invocation (or an applicable error).
+type FutureNameHistoryResult futureRaw
+
+// Receive waits for the response promised by the future and returns all
+// values of the name.
+func (r FutureNameHistoryResult) Receive() ([]nmcjson.NameHistoryResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameHistoryReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]nmcjson.NameHistoryResult), nil
+}
+
+// NameHistoryAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameHistory for the blocking version and more details.
+func (c *Client) NameHistoryAsync(name string, options *nmcjson.NameEncodingOptions) FutureNameHistoryResult {
+	cmd := nmcjson.NewNameHistoryCmd(name, options)
+	return FutureNameHistoryResult(c.sendCmd(cmd))
+}
+
+// NameHistory returns all values of name.
+func (c *Client) NameHistory(name string, options *nmcjson.NameEncodingOptions) ([]nmcjson.NameHistoryResult, error) {
+	return c.NameHistoryAsync(name, options).Receive()
+}
+
+// FutureNameListResult is a future promise to deliver the result of a
+// NameListAsync RPC invocation (or an applicable error).
+type FutureNameListResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// wallet's names.
+func (r FutureNameListResult) Receive() ([]nmcjson.NameListResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameListReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]nmcjson.NameListResult), nil
+}
+
+// NameListAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameList for the blocking version and more details.
+func (c *Client) NameListAsync(identifier *string, options *nmcjson.NameEncodingOptions) FutureNameListResult {
+	cmd := nmcjson.NewNameListCmd(identifier, options)
+	return FutureNameListResult(c.sendCmd(cmd))
+}
+
+// NameList returns the wallet's names, or only identifier when it is not nil.
+func (c *Client) NameList(identifier *string, options *nmcjson.NameEncodingOptions) ([]nmcjson.NameListResult, error) {
+	return c.NameListAsync(identifier, options).Receive()
+}
+
+// FutureNamePendingResult is a future promise to deliver the result of a
+// NamePendingAsync RPC invocation (or an applicable error).
+type FutureNamePendingResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// unconfirmed name operations.
+func (r FutureNamePendingResult) Receive() ([]nmcjson.NamePendingResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NamePendingReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]nmcjson.NamePendingResult), nil
+}
+
+// NamePendingAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NamePending for the blocking version and more details.
+func (c *Client) NamePendingAsync(name *string, options *nmcjson.NameEncodingOptions) FutureNamePendingResult {
+	cmd := nmcjson.NewNamePendingCmd(name, options)
+	return FutureNamePendingResult(c.sendCmd(cmd))
+}
+
+// NamePending returns the unconfirmed name operations, or only those on name
+// when it is not nil.
+func (c *Client) NamePending(name *string, options *nmcjson.NameEncodingOptions) ([]nmcjson.NamePendingResult, error) {
+	return c.NamePendingAsync(name, options).Receive()
+}
+
+// FutureNameScanResult is a future promise to deliver the result of a
+// NameScanAsync RPC invocation (or an applicable error).
+type FutureNameScanResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// scanned names.
+func (r FutureNameScanResult) Receive() ([]nmcjson.NameScanResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameScanReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]nmcjson.NameScanResult), nil
+}
+
+// NameScanAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameScan for the blocking version and more details.
+func (c *Client) NameScanAsync(startName *string, maxReturned *int, options *nmcjson.NameScanOptions) FutureNameScanResult {
+	cmd := nmcjson.NewNameScanCmd(startName, maxReturned, options)
+	return FutureNameScanResult(c.sendCmd(cmd))
+}
+
+// NameScan returns at most maxReturned names, starting at startName and
+// filtered by options.
+func (c *Client) NameScan(startName *string, maxReturned *int, options *nmcjson.NameScanOptions) ([]nmcjson.NameScanResult, error) {
+	return c.NameScanAsync(startName, maxReturned, options).Receive()
+}
+
+// FutureNameFilterResult is a future promise to deliver the result of a
+// NameFilterAsync RPC invocation (or an applicable error).
+type FutureNameFilterResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// matching names.
+func (r FutureNameFilterResult) Receive() ([]nmcjson.NameFilterResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameFilterReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.([]nmcjson.NameFilterResult), nil
+}
+
+// NameFilterAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See NameFilter for the blocking version and more details.
+func (c *Client) NameFilterAsync(regexp *string, maxAge, from, nb *int) FutureNameFilterResult {
+	if maxAge == nil && (from != nil || nb != nil) {
+		defaultMaxAge := c.params.DefaultFilterMaxAge
+		maxAge = &defaultMaxAge
+	}
+	cmd := nmcjson.NewNameFilterCmd(regexp, maxAge, from, nb, nil)
+	return FutureNameFilterResult(c.sendCmd(cmd))
+}
+
+// NameFilter returns the names matching regexp which were updated in the
+// last maxAge blocks.  A nil maxAge followed by from or nb is sent as the
+// DefaultFilterMaxAge of the Client params.
+func (c *Client) NameFilter(regexp *string, maxAge, from, nb *int) ([]nmcjson.NameFilterResult, error) {
+	return c.NameFilterAsync(regexp, maxAge, from, nb).Receive()
+}
+
+// FutureNameFilterStatResult is a future promise to deliver the result of a
+// NameFilterStatAsync RPC invocation (or an applicable error).
+type FutureNameFilterStatResult futureRaw
+
+// Receive waits for the response promised by the future and returns the
+// statistics of the matching names.
+func (r FutureNameFilterStatResult) Receive() (*nmcjson.NameFilterStatResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameFilterStatReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	stat := parsed.(nmcjson.NameFilterStatResult)
+	return &stat, nil
+}
+
+// NameFilterStatAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See NameFilterStat for the blocking version and more details.
+func (c *Client) NameFilterStatAsync(regexp *string, maxAge *int) FutureNameFilterStatResult {
+	from, nb, stat := 0, 0, nmcjson.NameFilterStat
+	if regexp == nil {
+		empty := ""
+		regexp = &empty
+	}
+	if maxAge == nil {
+		defaultMaxAge := c.params.DefaultFilterMaxAge
+		maxAge = &defaultMaxAge
+	}
+	cmd := nmcjson.NewNameFilterCmd(regexp, maxAge, &from, &nb, &stat)
+	return FutureNameFilterStatResult(c.sendCmd(cmd))
+}
+
+// NameFilterStat returns the number of names matching regexp which were
+// updated in the last maxAge blocks, along with the current block count.
+func (c *Client) NameFilterStat(regexp *string, maxAge *int) (*nmcjson.NameFilterStatResult, error) {
+	return c.NameFilterStatAsync(regexp, maxAge).Receive()
+}
+
+// FutureNameRawTransactionResult is a future promise to deliver the result of
+// a NameRawTransactionAsync RPC invocation (or an applicable error).
+type FutureNameRawTransactionResult futureRaw
+
+// Receive waits for the response promised by the future and returns the raw
+// transaction with the name operation attached.
+func (r FutureNameRawTransactionResult) Receive() (*nmcjson.NameRawTransactionResult, error) {
+	res, err := futureRaw(r).receive()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := nmcjson.NameRawTransactionReplyParse(res)
+	if err != nil {
+		return nil, err
+	}
+	result := parsed.(nmcjson.NameRawTransactionResult)
+	return &result, nil
+}
+
+// NameRawTransactionAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See NameRawTransaction for the blocking version and more details.
+func (c *Client) NameRawTransactionAsync(hexTx string, vout int, nameOp nmcjson.NameOp) FutureNameRawTransactionResult {
+	cmd, err := nmcjson.NewNameRawTransactionCmd(hexTx, vout, nameOp)
+	if err != nil {
+		return FutureNameRawTransactionResult{err: err}
+	}
+	return FutureNameRawTransactionResult(c.sendCmd(cmd))
+}
+
+// NameRawTransaction attaches nameOp to output vout of the raw transaction
+// hexTx.  The rand of the result is set for name_new operations.
+func (c *Client) NameRawTransaction(hexTx string, vout int, nameOp nmcjson.NameOp) (*nmcjson.NameRawTransactionResult, error) {
+	return c.NameRawTransactionAsync(hexTx, vout, nameOp).Receive()
+}
@@ -0,0 +1,128 @@
+package nmcrpcclient
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/nmctest"
+)
+
+// newTestClient returns a Client of a fresh nmctest server.
+func newTestClient(t *testing.T) (*Client, *nmctest.Server) {
+	t.Helper()
+	s := nmctest.NewServer(nil)
+	t.Cleanup(s.Close)
+	client, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(s.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Shutdown)
+	return New(client, s.Params()), s
+}
+
+func TestRoundTrip(t *testing.T) {
+	c, s := newTestClient(t)
+
+	nn, err := c.NameNew("d/example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(s.Params().MinFirstUpdateDepth)
+	first, err := c.NameFirstUpdate("d/example", nn.Rand, nn.Txid, "v1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(1)
+	hexEnc := &nmcjson.NameTxOptions{NameEncodingOptions: nmcjson.NameEncodingOptions{
+		ValueEncoding: nmcjson.EncodingHex,
+	}}
+	update, err := c.NameUpdate("d/example", "7632", hexEnc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	show, err := c.NameShow("d/example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Name != "d/example" || show.Value != "v2" || show.Txid != string(update) {
+		t.Fatalf("name_show: got %+v, want value %q txid %s", show, "v2", update)
+	}
+	history, err := c.NameHistory("d/example", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Txid != string(first) || history[1].Txid != string(update) {
+		t.Fatalf("name_history: got %+v, want txids %s and %s", history, first, update)
+	}
+	list, err := c.NameList(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "d/example" {
+		t.Fatalf("name_list: got %+v, want d/example", list)
+	}
+	pending, err := c.NamePending(nil, nil)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("name_pending: got %+v (%v), want none", pending, err)
+	}
+	scan, err := c.NameScan(nil, nil, &nmcjson.NameScanOptions{Prefix: "d/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan) != 1 || scan[0].Value != "v2" {
+		t.Fatalf("name_scan: got %+v, want d/example", scan)
+	}
+	// A nil regexp before a set maxAge is sent as the default.
+	filter, err := c.NameFilter(nil, btcjson.Int(10), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter) != 1 || filter[0].Name != "d/example" {
+		t.Fatalf("name_filter: got %+v, want d/example", filter)
+	}
+	// A nil maxAge before a set from is sent as the default of the params.
+	filter, err = c.NameFilter(nil, nil, btcjson.Int(0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter) != 1 {
+		t.Fatalf("name_filter from 0: got %+v, want d/example", filter)
+	}
+	stat, err := c.NameFilterStat(btcjson.String("^d/"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Count != 1 || stat.Blocks != s.Height() {
+		t.Fatalf("name_filter stat: got %+v, want 1 name at height %d", stat, s.Height())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := newTestClient(t)
+	if _, err := c.NameShow("d/missing", nil); !errors.Is(err, nmcjson.ErrNameNotFound) {
+		t.Fatalf("name_show: got %v, want %v", err, nmcjson.ErrNameNotFound)
+	}
+	if _, err := c.NameUpdate("d/missing", "v", nil); !errors.Is(err, nmcjson.ErrNameNotUpdatable) {
+		t.Fatalf("name_update: got %v, want %v", err, nmcjson.ErrNameNotUpdatable)
+	}
+	testnet := &nmcjson.NameTxOptions{DestAddress: "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth"}
+	if _, err := c.NameNew("d/example", testnet); !errors.Is(err, address.ErrWrongNetwork) {
+		t.Fatalf("name_new to a testnet address: got %v, want %v", err, address.ErrWrongNetwork)
+	}
+	var rpcErr *nmcjson.RPCError
+	if _, err := c.NameRawTransaction("00", 0, nmcjson.NameOp{Op: nmcjson.OpNameNew, Name: "d/x"}); !errors.As(err, &rpcErr) {
+		t.Fatalf("namerawtransaction of a malformed transaction: got %v, want an RPC error", err)
+	}
+}
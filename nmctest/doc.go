@@ -0,0 +1,18 @@
+/*
+Package nmctest provides an in-process fake namecoind for tests.
+
+NewServer starts an httptest server which implements every command
+registered by nmcjson, along with getblockcount, on top of an in-memory name
+database.  The chain only advances when Generate is called, so tests control
+when a name_firstupdate becomes valid and when names expire, following the
+params given to NewServer.  With nil params names expire 36000 blocks after
+their last update, and with nmcjson.RegTestParams after 30 blocks.  Replies
+have the shapes expected by the nmcjson ReplyParse functions.
+
+Name operations are mined in the block at the tip as soon as they are sent,
+which gives the tip a new hash.  getblockhash, getblockheader and getblock
+(verbosity 1 and 2) serve the blocks, which are ten minutes apart, and Reorg
+replaces the last blocks, undoing their name operations, so code following
+the chain can be tested against reorganisations.
+*/
+package nmctest
@@ -0,0 +1,784 @@
+package nmctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+const (
+	// defaultScanMax is the default number of names returned by name_scan.
+	defaultScanMax = 500
+
+	// blockSpacing is the time in seconds between the blocks produced by
+	// Generate.
+	blockSpacing = 600
+)
+
+// Messages of the errors returned by the server.
+const (
+	msgNameNotFound       = "name not found"
+	msgNameExpired        = "name expired"
+	msgNameActive         = "this name is already active"
+	msgNameNotUpdatable   = "this name can not be updated"
+	msgNameNewNotFound    = "name_new transaction not found"
+	msgNameNewNotMature   = "name_new is not mature for FIRST_UPDATE"
+	msgRandMismatch       = "rand does not match the name_new"
+	msgInvalidRegexp      = "invalid regexp"
+	msgHeightOutOfRange   = "Block height out of range"
+	msgBlockNotFound      = "Block not found"
+	msgVerbosity          = "only verbosity 1 and 2 are supported"
+	msgHeaderVerbose      = "only verbose headers are supported"
+	msgMethodNotSupported = "method not supported"
+)
+
+// nameRecord is one value of a name, as set by a name_firstupdate or
+// name_update.
+type nameRecord struct {
+	value   string
+	txid    string
+	address string
+	height  int64
+}
+
+// nameNew is a pending name_new reservation.
+type nameNew struct {
+	name       string
+	commitment []byte
+	height     int64
+}
+
+// block is a block of the chain and the name transactions mined in it.
+type block struct {
+	hash string
+	time int64
+	txs  []blockTx
+}
+
+// blockTx is a name transaction.  Its name output is output 0, and its name
+// input, if any, is output 0 of the transaction input.
+type blockTx struct {
+	txid    string
+	input   string
+	script  []byte
+	address string
+}
+
+// Server is an in-process namecoind replacement which serves the nmcjson
+// commands over HTTP from an in-memory name database.  Blocks are only
+// produced when Generate is called.
+type Server struct {
+	*httptest.Server
+
+	params  *nmcjson.Params
+	mtx     sync.Mutex
+	height  int64
+	blocks  []*block // indexed by height
+	history map[string][]nameRecord
+	newTxs  map[string]*nameNew
+	wallet  map[string]bool // name -> still owned by the wallet
+	address string
+}
+
+// DefaultParams are the params of a Server started with nil params.  They
+// are those of mainnet, except that names expire 36000 blocks after their
+// last update at any height, as on mainnet since block 48000, rather than
+// after 12000 blocks at the low heights of a test chain.
+var DefaultParams = func() nmcjson.Params {
+	p := nmcjson.MainNetParams
+	p.NameExpirationDepth = func(int64) int64 { return 36000 }
+	return p
+}()
+
+// NewServer starts and returns a new Server following params, or
+// DefaultParams if params is nil.  The caller should call Close when
+// finished, to shut it down.
+func NewServer(params *nmcjson.Params) *Server {
+	if params == nil {
+		params = &DefaultParams
+	}
+	nmcjson.Init()
+	s := &Server{
+		params:  params,
+		blocks:  []*block{{hash: randomHex(32), time: time.Now().Unix()}},
+		history: make(map[string][]nameRecord),
+		newTxs:  make(map[string]*nameNew),
+		wallet:  make(map[string]bool),
+	}
+	s.address = s.randomAddress()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// ConnConfig returns a connection configuration for an nmcjson.Client which
+// talks to s.
+func (s *Server) ConnConfig() *nmcjson.ConnConfig {
+	return &nmcjson.ConnConfig{
+		Host:       strings.TrimPrefix(s.URL, "http://"),
+		DisableTLS: true,
+		Params:     s.params,
+	}
+}
+
+// Params returns the params followed by s.
+func (s *Server) Params() *nmcjson.Params {
+	return s.params
+}
+
+// Generate advances the chain by n blocks and returns the new height.  The
+// blocks are timestamped ten minutes apart.
+func (s *Server) Generate(n int64) int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i := int64(0); i < n; i++ {
+		prev := s.blocks[len(s.blocks)-1]
+		s.blocks = append(s.blocks, &block{hash: randomHex(32), time: prev.time + blockSpacing})
+	}
+	s.height += n
+	return s.height
+}
+
+// Reorg replaces the last depth blocks with as many empty blocks, so the
+// height is unchanged but the blocks have new hashes.  The name operations
+// mined in the replaced blocks are undone.  The genesis block is never
+// replaced.
+func (s *Server) Reorg(depth int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if depth > s.height {
+		depth = s.height
+	}
+	fork := s.height - depth
+	for h := fork + 1; h <= s.height; h++ {
+		s.blocks[h] = &block{hash: randomHex(32), time: s.blocks[h].time}
+	}
+	for name, records := range s.history {
+		kept := records
+		for len(kept) > 0 && kept[len(kept)-1].height > fork {
+			kept = kept[:len(kept)-1]
+		}
+		if len(kept) == 0 {
+			delete(s.history, name)
+			delete(s.wallet, name)
+			continue
+		}
+		s.history[name] = kept
+		s.wallet[name] = kept[len(kept)-1].address == s.address
+	}
+	for txid, reservation := range s.newTxs {
+		if reservation.height > fork {
+			delete(s.newTxs, txid)
+		}
+	}
+}
+
+// mine adds a name transaction to the block at the tip.  The tip gets a new
+// hash, as if it was replaced by a block with the additional transaction.
+func (s *Server) mine(txid, input string, script *namescript.Script, address string) {
+	// Scripts of names and values too long for Core are left empty; the
+	// operations are still recorded.
+	encoded, _ := script.Encode()
+	tip := s.blocks[s.height]
+	tip.hash = randomHex(32)
+	tip.txs = append(tip.txs, blockTx{txid: txid, input: input, script: encoded, address: address})
+}
+
+// Height returns the current block height.
+func (s *Server) Height() int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.height
+}
+
+// Address returns the address of the server's wallet.
+func (s *Server) Address() string {
+	return s.address
+}
+
+// handle serves a single JSON-RPC request.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var request btcjson.Request
+	if err := json.Unmarshal(body, &request); err != nil {
+		s.reply(w, nil, nil, btcjson.ErrRPCParse)
+		return
+	}
+	cmd, err := btcjson.UnmarshalCmd(&request)
+	if err != nil {
+		s.reply(w, request.ID, nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mtx.Lock()
+	result, rpcErr := s.execute(cmd)
+	s.mtx.Unlock()
+	s.reply(w, request.ID, result, rpcErr)
+}
+
+// reply writes a JSON-RPC response.
+func (s *Server) reply(w http.ResponseWriter, id interface{}, result interface{}, rpcErr *btcjson.RPCError) {
+	marshalled, err := btcjson.MarshalResponse(btcjson.RpcVersion1, id, result, rpcErr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if rpcErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Write(marshalled)
+}
+
+// execute runs cmd against the name database.  The caller must hold mtx.
+func (s *Server) execute(cmd interface{}) (interface{}, *btcjson.RPCError) {
+	switch c := cmd.(type) {
+	case *btcjson.GetBlockCountCmd:
+		return s.height, nil
+	case *btcjson.GetBlockHashCmd:
+		if c.Index < 0 || c.Index > s.height {
+			// namecoind, unlike btcd, reports the height as an
+			// invalid parameter.
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgHeightOutOfRange)
+		}
+		return s.blocks[c.Index].hash, nil
+	case *btcjson.GetBlockCmd:
+		return s.getBlock(c)
+	case *btcjson.GetBlockHeaderCmd:
+		return s.getBlockHeader(c)
+	case *nmcjson.NameNewCmd:
+		return s.nameNew(c)
+	case *nmcjson.NameFirstUpdateCmd:
+		return s.nameFirstUpdate(c)
+	case *nmcjson.NameUpdateCmd:
+		return s.nameUpdate(c)
+	case *nmcjson.NameShowCmd:
+		return s.nameShow(c)
+	case *nmcjson.NameHistoryCmd:
+		return s.nameHistory(c)
+	case *nmcjson.NameListCmd:
+		return s.nameList(c)
+	case *nmcjson.NameScanCmd:
+		return s.nameScan(c)
+	case *nmcjson.NameFilterCmd:
+		return s.nameFilter(c)
+	case *nmcjson.NamePendingCmd:
+		// Operations are confirmed as soon as they are sent, so the
+		// mempool is always empty.
+		return []nmcjson.NamePendingResult{}, nil
+	case *nmcjson.NameRawTransactionCmd:
+		// The transaction is only built, so the node state is not
+		// needed.
+		res, err := namescript.NameRawTransaction(c.HexTx, c.Vout, c.NameOp)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+		}
+		return res, nil
+	}
+	return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, msgMethodNotSupported)
+}
+
+func (s *Server) nameNew(c *nmcjson.NameNewCmd) (interface{}, *btcjson.RPCError) {
+	enc := txEncodings(c.Options)
+	name, rpcErr := decodeParam(c.Name, enc.NameEncoding)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	txid := randomHex(32)
+	var salt string
+	if c.Options != nil {
+		salt = c.Options.Rand
+	}
+	if salt == "" {
+		var err error
+		if salt, err = nmcjson.NewRand(); err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, err.Error())
+		}
+	}
+	commitment, err := nmcjson.NameCommitment(name, salt)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	s.newTxs[txid] = &nameNew{
+		name:       name,
+		commitment: commitment,
+		height:     s.height,
+	}
+	s.mine(txid, "", namescript.NewNameNew(commitment, s.addressScript(s.address)), s.address)
+	return []string{txid, salt}, nil
+}
+
+func (s *Server) nameFirstUpdate(c *nmcjson.NameFirstUpdateCmd) (interface{}, *btcjson.RPCError) {
+	name, value, rpcErr := decodeNameValue(c.Name, c.Value, txEncodings(c.Options))
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	reservation, ok := s.newTxs[c.Txid]
+	if !ok || reservation.name != name {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameNewNotFound)
+	}
+	if nmcjson.VerifyNameCommitment(name, c.Rand, reservation.commitment) != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgRandMismatch)
+	}
+	if s.height-reservation.height < s.params.MinFirstUpdateDepth {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCVerifyRejected, msgNameNewNotMature)
+	}
+	if records, ok := s.history[name]; ok && !s.expired(records[len(records)-1]) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameActive)
+	}
+	delete(s.newTxs, c.Txid)
+	rand, _ := hex.DecodeString(c.Rand)
+	return s.apply(name, c.Txid, rand, value, c.Options), nil
+}
+
+func (s *Server) nameUpdate(c *nmcjson.NameUpdateCmd) (interface{}, *btcjson.RPCError) {
+	name, value, rpcErr := decodeNameValue(c.Name, c.Value, txEncodings(c.Options))
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	records, ok := s.history[name]
+	if !ok || !s.wallet[name] || s.expired(records[len(records)-1]) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameNotUpdatable)
+	}
+	return s.apply(name, records[len(records)-1].txid, nil, value, c.Options), nil
+}
+
+// apply records a new value for name at the current height and returns the
+// txid of the operation, a name_firstupdate when rand is set and a
+// name_update otherwise.  input is the txid of the name_new or previous
+// operation it spends.  The name is sent to options.DestAddress if set.
+func (s *Server) apply(name, input string, rand []byte, value string, options *nmcjson.NameTxOptions) string {
+	record := nameRecord{
+		value:   value,
+		txid:    randomHex(32),
+		address: s.address,
+		height:  s.height,
+	}
+	if options != nil && options.DestAddress != "" {
+		record.address = options.DestAddress
+	}
+	s.history[name] = append(s.history[name], record)
+	s.wallet[name] = record.address == s.address
+
+	addressScript := s.addressScript(record.address)
+	script := namescript.NewNameUpdate([]byte(name), []byte(value), addressScript)
+	if rand != nil {
+		script = namescript.NewNameFirstUpdate([]byte(name), rand, []byte(value), addressScript)
+	}
+	s.mine(record.txid, input, script, record.address)
+	return record.txid
+}
+
+// addressScript returns the output script paying to addr, or an empty script
+// if addr is not valid on the network of s.
+func (s *Server) addressScript(addr string) []byte {
+	a, err := address.Decode(addr, s.params)
+	if err != nil {
+		return nil
+	}
+	return a.Script()
+}
+
+// findBlock returns the height of the block with the given hash.
+func (s *Server) findBlock(hash string) (int64, *btcjson.RPCError) {
+	for h, b := range s.blocks {
+		if b.hash == hash {
+			return int64(h), nil
+		}
+	}
+	return 0, btcjson.NewRPCError(btcjson.ErrRPCBlockNotFound, msgBlockNotFound)
+}
+
+// neighbours returns the hashes of the blocks before and after height, if
+// any.
+func (s *Server) neighbours(height int64) (prev, next string) {
+	if height > 0 {
+		prev = s.blocks[height-1].hash
+	}
+	if height < s.height {
+		next = s.blocks[height+1].hash
+	}
+	return prev, next
+}
+
+func (s *Server) getBlockHeader(c *btcjson.GetBlockHeaderCmd) (interface{}, *btcjson.RPCError) {
+	if c.Verbose != nil && !*c.Verbose {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgHeaderVerbose)
+	}
+	height, rpcErr := s.findBlock(c.Hash)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	b := s.blocks[height]
+	prev, next := s.neighbours(height)
+	return btcjson.GetBlockHeaderVerboseResult{
+		Hash:          b.hash,
+		Confirmations: s.height - height + 1,
+		Height:        int32(height),
+		Time:          b.time,
+		PreviousHash:  prev,
+		NextHash:      next,
+	}, nil
+}
+
+func (s *Server) getBlock(c *btcjson.GetBlockCmd) (interface{}, *btcjson.RPCError) {
+	height, rpcErr := s.findBlock(c.Hash)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	b := s.blocks[height]
+	prev, next := s.neighbours(height)
+	confirmations := s.height - height + 1
+
+	verbosity := 1
+	if c.Verbosity != nil {
+		verbosity = *c.Verbosity
+	}
+	switch verbosity {
+	case 1:
+		txids := make([]string, 0, len(b.txs))
+		for _, tx := range b.txs {
+			txids = append(txids, tx.txid)
+		}
+		return btcjson.GetBlockVerboseResult{
+			Hash:          b.hash,
+			Confirmations: confirmations,
+			Height:        height,
+			Time:          b.time,
+			Tx:            txids,
+			PreviousHash:  prev,
+			NextHash:      next,
+		}, nil
+	case 2:
+		txs := make([]btcjson.TxRawResult, 0, len(b.txs))
+		for _, tx := range b.txs {
+			var vin []btcjson.Vin
+			if tx.input != "" {
+				vin = []btcjson.Vin{{Txid: tx.input}}
+			}
+			txs = append(txs, btcjson.TxRawResult{
+				Txid:    tx.txid,
+				Hash:    tx.txid,
+				Version: namescript.NamecoinTxVersion,
+				Vin:     vin,
+				Vout: []btcjson.Vout{{
+					// The 0.01 NMC locked in name outputs.
+					Value: 0.01,
+					ScriptPubKey: btcjson.ScriptPubKeyResult{
+						Hex:       hex.EncodeToString(tx.script),
+						Addresses: []string{tx.address},
+					},
+				}},
+				BlockHash:     b.hash,
+				Confirmations: uint64(confirmations),
+			})
+		}
+		return btcjson.GetBlockVerboseTxResult{
+			Hash:          b.hash,
+			Confirmations: confirmations,
+			Height:        height,
+			Time:          b.time,
+			Tx:            txs,
+			PreviousHash:  prev,
+			NextHash:      next,
+		}, nil
+	}
+	return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgVerbosity)
+}
+
+func (s *Server) nameShow(c *nmcjson.NameShowCmd) (interface{}, *btcjson.RPCError) {
+	var enc nmcjson.NameEncodingOptions
+	if c.Options != nil {
+		enc = c.Options.NameEncodingOptions
+	}
+	name, rpcErr := decodeParam(c.Name, enc.NameEncoding)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	records, ok := s.history[name]
+	if !ok {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameNotFound)
+	}
+	record := records[len(records)-1]
+	if c.Options != nil && c.Options.AllowExpired != nil && !*c.Options.AllowExpired && s.expired(record) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameExpired)
+	}
+	return s.showResult(name, record, enc), nil
+}
+
+func (s *Server) nameHistory(c *nmcjson.NameHistoryCmd) (interface{}, *btcjson.RPCError) {
+	var enc nmcjson.NameEncodingOptions
+	if c.Options != nil {
+		enc = *c.Options
+	}
+	name, rpcErr := decodeParam(c.Name, enc.NameEncoding)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	records, ok := s.history[name]
+	if !ok {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCWallet, msgNameNotFound)
+	}
+	results := make([]nmcjson.NameHistoryResult, 0, len(records))
+	for _, record := range records {
+		results = append(results, nmcjson.NameHistoryResult(s.showResult(name, record, enc)))
+	}
+	return results, nil
+}
+
+func (s *Server) nameList(c *nmcjson.NameListCmd) (interface{}, *btcjson.RPCError) {
+	var enc nmcjson.NameEncodingOptions
+	if c.Options != nil {
+		enc = *c.Options
+	}
+	var filter string
+	if c.Identifier != nil {
+		var rpcErr *btcjson.RPCError
+		filter, rpcErr = decodeParam(*c.Identifier, enc.NameEncoding)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	results := make([]nmcjson.NameListResult, 0, len(s.wallet))
+	for _, name := range s.sortedNames() {
+		owned, ok := s.wallet[name]
+		if !ok || (filter != "" && filter != name) {
+			continue
+		}
+		show := s.showResult(name, s.current(name), enc)
+		results = append(results, nmcjson.NameListResult{
+			NameValue: nmcjson.NameValue{
+				Name:          show.Name,
+				Value:         show.Value,
+				NameEncoding:  show.NameEncoding,
+				ValueEncoding: show.ValueEncoding,
+			},
+			Txid:        show.Txid,
+			Vout:        show.Vout,
+			Address:     show.Address,
+			Height:      show.Height,
+			ExpiresIn:   show.ExpiresIn,
+			Expired:     show.Expired,
+			Transferred: !owned,
+			NameError:   show.NameError,
+			ValueError:  show.ValueError,
+		})
+	}
+	return results, nil
+}
+
+func (s *Server) nameScan(c *nmcjson.NameScanCmd) (interface{}, *btcjson.RPCError) {
+	var opts nmcjson.NameScanOptions
+	if c.Options != nil {
+		opts = *c.Options
+	}
+	start, max := "", defaultScanMax
+	if c.StartName != nil {
+		var rpcErr *btcjson.RPCError
+		start, rpcErr = decodeParam(*c.StartName, opts.NameEncoding)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+	if c.MaxReturned != nil {
+		max = *c.MaxReturned
+	}
+	var re *regexp.Regexp
+	if opts.Regexp != "" {
+		var err error
+		re, err = regexp.Compile(opts.Regexp)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgInvalidRegexp)
+		}
+	}
+
+	results := make([]nmcjson.NameScanResult, 0)
+	for _, name := range s.sortedNames() {
+		if len(results) >= max {
+			break
+		}
+		if name < start || !strings.HasPrefix(name, opts.Prefix) {
+			continue
+		}
+		if re != nil && !re.MatchString(name) {
+			continue
+		}
+		record := s.current(name)
+		confs := s.height - record.height + 1
+		if opts.MinConf != nil && confs < int64(*opts.MinConf) {
+			continue
+		}
+		if opts.MaxConf != nil && confs > int64(*opts.MaxConf) {
+			continue
+		}
+		results = append(results, nmcjson.NameScanResult(s.showResult(name, record, opts.NameEncodingOptions)))
+	}
+	return results, nil
+}
+
+func (s *Server) nameFilter(c *nmcjson.NameFilterCmd) (interface{}, *btcjson.RPCError) {
+	pattern, maxAge, from, nb := "", s.params.DefaultFilterMaxAge, 0, 0
+	if c.Regexp != nil {
+		pattern = *c.Regexp
+	}
+	if c.MaxAge != nil {
+		maxAge = *c.MaxAge
+	}
+	if c.From != nil {
+		from = *c.From
+	}
+	if c.Nb != nil {
+		nb = *c.Nb
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, msgInvalidRegexp)
+	}
+
+	var matches []nmcjson.NameFilterResult
+	for _, name := range s.sortedNames() {
+		record := s.current(name)
+		if s.expired(record) || (maxAge != 0 && s.height-record.height >= int64(maxAge)) {
+			continue
+		}
+		if !re.MatchString(name) {
+			continue
+		}
+		show := s.showResult(name, record, nmcjson.NameEncodingOptions{})
+		matches = append(matches, nmcjson.NameFilterResult(show))
+	}
+
+	if c.IsStat() {
+		return nmcjson.NameFilterStatResult{
+			Blocks: s.height,
+			Count:  len(matches),
+		}, nil
+	}
+	results := make([]nmcjson.NameFilterResult, 0)
+	for i, match := range matches {
+		if i < from {
+			continue
+		}
+		if nb != 0 && len(results) >= nb {
+			break
+		}
+		results = append(results, match)
+	}
+	return results, nil
+}
+
+// current returns the latest record of name.  The name must exist.
+func (s *Server) current(name string) nameRecord {
+	records := s.history[name]
+	return records[len(records)-1]
+}
+
+// expired returns whether record has expired at the current height.
+func (s *Server) expired(record nameRecord) bool {
+	return s.params.IsExpired(record.height, s.height)
+}
+
+// showResult returns the name_show view of record, with the name and value
+// encoded as requested by enc.
+func (s *Server) showResult(name string, record nameRecord, enc nmcjson.NameEncodingOptions) nmcjson.NameShowResult {
+	result := nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{
+			NameEncoding:  enc.NameEncoding,
+			ValueEncoding: enc.ValueEncoding,
+		},
+		Txid:      record.txid,
+		Address:   record.address,
+		Height:    record.height,
+		ExpiresIn: s.params.ExpiresIn(record.height, s.height),
+		Expired:   s.expired(record),
+	}
+	var err error
+	if result.Name, err = enc.NameEncoding.Encode([]byte(name)); err != nil {
+		result.NameError = err.Error()
+	}
+	if result.Value, err = enc.ValueEncoding.Encode([]byte(record.value)); err != nil {
+		result.ValueError = err.Error()
+	}
+	return result
+}
+
+// txEncodings returns the encodings requested by the options of a name
+// operation.
+func txEncodings(options *nmcjson.NameTxOptions) nmcjson.NameEncodingOptions {
+	if options == nil {
+		return nmcjson.NameEncodingOptions{}
+	}
+	return options.NameEncodingOptions
+}
+
+// decodeParam returns the raw bytes, as a string, of a name or value
+// parameter encoded with enc.
+func decodeParam(param string, enc nmcjson.Encoding) (string, *btcjson.RPCError) {
+	b, err := enc.Decode(param)
+	if err != nil {
+		return "", btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	return string(b), nil
+}
+
+// decodeNameValue decodes the name and value parameters of a name
+// operation.
+func decodeNameValue(name, value string, enc nmcjson.NameEncodingOptions) (string, string, *btcjson.RPCError) {
+	rawName, rpcErr := decodeParam(name, enc.NameEncoding)
+	if rpcErr != nil {
+		return "", "", rpcErr
+	}
+	rawValue, rpcErr := decodeParam(value, enc.ValueEncoding)
+	if rpcErr != nil {
+		return "", "", rpcErr
+	}
+	return rawName, rawValue, nil
+}
+
+// sortedNames returns all names in the database in ascending order.
+func (s *Server) sortedNames() []string {
+	names := make([]string, 0, len(s.history))
+	for name := range s.history {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// randomAddress returns a random P2PKH address of the network of s.
+func (s *Server) randomAddress() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	a, err := address.NewPubKeyHash(b, s.params)
+	if err != nil {
+		panic(err)
+	}
+	return a.String()
+}
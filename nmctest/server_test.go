@@ -0,0 +1,255 @@
+package nmctest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// newTestClient returns a client of a fresh server following params.
+func newTestClient(t *testing.T, params *nmcjson.Params) (*nmcjson.Client, *Server) {
+	t.Helper()
+	s := NewServer(params)
+	t.Cleanup(s.Close)
+	client, err := nmcjson.NewClient(s.ConnConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, s
+}
+
+// register registers name with value in the wallet of s and returns the
+// txid of its name_firstupdate.
+func register(t *testing.T, client *nmcjson.Client, s *Server, name, value string) string {
+	t.Helper()
+	ctx := context.Background()
+	nn, err := client.NameNew(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(s.Params().MinFirstUpdateDepth)
+	txid, err := client.NameFirstUpdate(ctx, name, nn.Rand, nn.Txid, value, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(txid)
+}
+
+func TestFirstUpdateDepth(t *testing.T) {
+	ctx := context.Background()
+	client, s := newTestClient(t, nil)
+	depth := s.Params().MinFirstUpdateDepth
+
+	nn, err := client.NameNew(ctx, "d/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(depth - 1)
+	_, err = client.NameFirstUpdate(ctx, "d/example", nn.Rand, nn.Txid, "v", "")
+	if !errors.Is(err, nmcjson.ErrFirstUpdateTooEarly) {
+		t.Fatalf("after %d blocks: got %v, want %v", depth-1, err, nmcjson.ErrFirstUpdateTooEarly)
+	}
+	s.Generate(1)
+	if _, err := client.NameFirstUpdate(ctx, "d/example", strings.Repeat("00", nmcjson.RandLength), nn.Txid, "v", ""); err == nil {
+		t.Fatal("wrong rand: got no error")
+	}
+	txid, err := client.NameFirstUpdate(ctx, "d/example", nn.Rand, nn.Txid, "v", "")
+	if err != nil {
+		t.Fatalf("after %d blocks: %v", depth, err)
+	}
+	show, err := client.NameShow(ctx, "d/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Txid != string(txid) || show.Value != "v" || show.Height != s.Height() {
+		t.Fatalf("name_show: got %+v, want txid %s value %q height %d", show, txid, "v", s.Height())
+	}
+	if _, err := client.NameFirstUpdate(ctx, "d/example", nn.Rand, nn.Txid, "v", ""); err == nil {
+		t.Fatal("reused name_new: got no error")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+	client, s := newTestClient(t, nil)
+	if got := s.Generate(3); got != 3 {
+		t.Fatalf("Generate: got height %d, want 3", got)
+	}
+	if got, err := client.GetBlockCount(ctx); err != nil || got != 3 {
+		t.Fatalf("getblockcount: got %d (%v), want 3", got, err)
+	}
+	var prev int64
+	for h := int64(0); h <= 3; h++ {
+		hash, err := client.GetBlockHash(ctx, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := client.GetBlockHeaderVerbose(ctx, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Height != int32(h) {
+			t.Fatalf("header of block %d: got height %d", h, header.Height)
+		}
+		if h > 0 && header.Time-prev != blockSpacing {
+			t.Fatalf("block %d: %d seconds after the previous one, want %d", h, header.Time-prev, blockSpacing)
+		}
+		prev = header.Time
+	}
+	if _, err := client.GetBlockHash(ctx, 4); err == nil {
+		t.Fatal("getblockhash above the tip: got no error")
+	}
+}
+
+func TestReorg(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int64 // blocks replaced, counted from the tip
+		kept  bool  // whether the registration survives
+	}{
+		{"above registration", 2, true},
+		{"registration block", 3, false},
+		{"beyond genesis", 100, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, s := newTestClient(t, nil)
+			register(t, client, s, "d/example", "v")
+			s.Generate(2)
+			height := s.Height()
+			hashes := make([]string, height+1)
+			for h := range hashes {
+				hash, err := client.GetBlockHash(ctx, int64(h))
+				if err != nil {
+					t.Fatal(err)
+				}
+				hashes[h] = hash
+			}
+
+			s.Reorg(test.depth)
+			if s.Height() != height {
+				t.Fatalf("height: got %d, want %d", s.Height(), height)
+			}
+			for h, old := range hashes {
+				hash, err := client.GetBlockHash(ctx, int64(h))
+				if err != nil {
+					t.Fatal(err)
+				}
+				replaced := h > 0 && int64(h) > height-test.depth
+				if (hash != old) != replaced {
+					t.Fatalf("block %d: replaced %v, want %v", h, hash != old, replaced)
+				}
+			}
+			_, err := client.NameShow(ctx, "d/example")
+			switch {
+			case test.kept && err != nil:
+				t.Fatalf("name_show: %v", err)
+			case !test.kept && !errors.Is(err, nmcjson.ErrNameNotFound):
+				t.Fatalf("name_show: got %v, want %v", err, nmcjson.ErrNameNotFound)
+			}
+			names, err := client.NameList(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(names) == 1) != test.kept {
+				t.Fatalf("name_list: got %d names, want kept %v", len(names), test.kept)
+			}
+		})
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *nmcjson.Params
+		depth  int64
+	}{
+		{"default", nil, 36000},
+		{"regtest", &nmcjson.RegTestParams, 30},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, s := newTestClient(t, test.params)
+			register(t, client, s, "d/example", "v")
+
+			s.Generate(test.depth - 1)
+			show, err := client.NameShow(ctx, "d/example")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if show.Expired || show.ExpiresIn != 1 {
+				t.Fatalf("one block before expiry: got expired %v expires_in %d, want false 1", show.Expired, show.ExpiresIn)
+			}
+
+			s.Generate(1)
+			show, err = client.NameShow(ctx, "d/example")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !show.Expired || show.ExpiresIn != 0 {
+				t.Fatalf("at expiry: got expired %v expires_in %d, want true 0", show.Expired, show.ExpiresIn)
+			}
+			if _, err := client.NameUpdate(ctx, "d/example", "w", ""); !errors.Is(err, nmcjson.ErrNameNotUpdatable) {
+				t.Fatalf("name_update of an expired name: got %v, want %v", err, nmcjson.ErrNameNotUpdatable)
+			}
+			// An expired name can be registered again.
+			register(t, client, s, "d/example", "w")
+		})
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	ctx := context.Background()
+	client, s := newTestClient(t, nil)
+	hexNames := nmcjson.NameEncodingOptions{NameEncoding: nmcjson.EncodingHex}
+	hexValues := nmcjson.NameEncodingOptions{ValueEncoding: nmcjson.EncodingHex}
+
+	// The names and values of the parameters and results are hex encoded
+	// as requested: 642f6578616d706c65 is d/example and 7631 is v1.
+	nn, err := client.NameNewWithOptions(ctx, "642f6578616d706c65", &nmcjson.NameTxOptions{NameEncodingOptions: hexNames})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(s.Params().MinFirstUpdateDepth)
+	if _, err := client.NameFirstUpdateWithOptions(ctx, "d/example", nn.Rand, nn.Txid, "7631",
+		&nmcjson.NameTxOptions{NameEncodingOptions: hexValues}); err != nil {
+		t.Fatal(err)
+	}
+
+	show, err := client.NameShowWithOptions(ctx, "642f6578616d706c65", &nmcjson.NameShowOptions{NameEncodingOptions: hexNames})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, err := show.NameBytes(); err != nil || string(name) != "d/example" || show.Value != "v1" {
+		t.Fatalf("name_show: got %+v, want the hex of d/example and v1", show)
+	}
+	history, err := client.NameHistoryWithOptions(ctx, "d/example", &hexValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Value != "7631" {
+		t.Fatalf("name_history: got %+v, want one hex value", history)
+	}
+	if value, err := history[0].ValueBytes(); err != nil || string(value) != "v1" {
+		t.Fatalf("name_history value: got %q (%v), want %q", value, err, "v1")
+	}
+	list, err := client.NameListWithOptions(ctx, "", &hexNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "642f6578616d706c65" {
+		t.Fatalf("name_list: got %+v, want the hex of d/example", list)
+	}
+	scan, err := client.NameScanWithOptions(ctx, "", 0, &nmcjson.NameScanOptions{NameEncodingOptions: hexValues})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scan) != 1 || scan[0].Value != "7631" {
+		t.Fatalf("name_scan: got %+v, want one hex value", scan)
+	}
+}
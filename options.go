@@ -0,0 +1,100 @@
+package nmcjson
+
+import (
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Encoding is the encoding of a name or value in JSON-RPC commands and
+// results, as understood by Namecoin Core.
+type Encoding string
+
+const (
+	// EncodingASCII restricts names and values to printable ASCII.
+	EncodingASCII Encoding = "ascii"
+
+	// EncodingUTF8 allows any valid UTF-8 string.
+	EncodingUTF8 Encoding = "utf8"
+
+	// EncodingHex hex encodes the raw bytes, so any binary data can be
+	// used.
+	EncodingHex Encoding = "hex"
+)
+
+// Encode returns b encoded with e.  An empty Encoding passes b through
+// unchanged.
+func (e Encoding) Encode(b []byte) (string, error) {
+	switch e {
+	case "":
+		return string(b), nil
+	case EncodingASCII:
+		for _, c := range b {
+			if c < 0x20 || c > 0x7e {
+				return "", fmt.Errorf("byte %#02x is not printable ascii", c)
+			}
+		}
+		return string(b), nil
+	case EncodingUTF8:
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("data is not valid utf8")
+		}
+		return string(b), nil
+	case EncodingHex:
+		return hex.EncodeToString(b), nil
+	}
+	return "", fmt.Errorf("unknown encoding %q", string(e))
+}
+
+// Decode returns the raw bytes of s, which is encoded with e.  An empty
+// Encoding passes s through unchanged.
+func (e Encoding) Decode(s string) ([]byte, error) {
+	if e == EncodingHex {
+		return hex.DecodeString(s)
+	}
+	b := []byte(s)
+	if _, err := e.Encode(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NameEncodingOptions selects the encoding of names and values in a command
+// and its result.  Empty fields use the server's default.
+type NameEncodingOptions struct {
+	NameEncoding  Encoding `json:"nameEncoding,omitempty"`
+	ValueEncoding Encoding `json:"valueEncoding,omitempty"`
+}
+
+// NameShowOptions models the options object of the name_show command.
+type NameShowOptions struct {
+	NameEncodingOptions
+	AllowExpired *bool `json:"allowExpired,omitempty"`
+}
+
+// NameScanOptions models the options object of the name_scan command.
+type NameScanOptions struct {
+	NameEncodingOptions
+	MinConf *int   `json:"minConf,omitempty"`
+	MaxConf *int   `json:"maxConf,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	Regexp  string `json:"regexp,omitempty"`
+}
+
+// NameTxOptions models the options object of the name_new,
+// name_firstupdate and name_update commands.
+type NameTxOptions struct {
+	NameEncodingOptions
+
+	// DestAddress is the address the name is sent to.  When empty, the
+	// name stays in the wallet.
+	DestAddress string `json:"destAddress,omitempty"`
+
+	// SendCoins maps addresses to amounts, in NMC, which are paid in the
+	// same transaction.
+	SendCoins map[string]float64 `json:"sendCoins,omitempty"`
+
+	// Rand is the hex encoded salt of a name_new.  When empty, namecoind
+	// generates one and returns it.  It is only valid for name_new.
+	Rand string `json:"rand,omitempty"`
+}
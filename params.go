@@ -0,0 +1,133 @@
+package nmcjson
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcutil"
+)
+
+// Params defines a Namecoin network: the consensus rules of its names, the
+// defaults of its RPC server and the encoding of its addresses.
+type Params struct {
+	// Name identifies the network for NetworkParams.
+	Name string
+
+	// MinFirstUpdateDepth is the number of blocks which must follow a
+	// name_new before its name_firstupdate is valid.
+	MinFirstUpdateDepth int64
+
+	// NameExpirationDepth returns the number of blocks after its last
+	// update at which a name expires, when checked at height.
+	NameExpirationDepth func(height int64) int64
+
+	// NameLockedAmount is the amount namecoind locks in name outputs.
+	NameLockedAmount btcutil.Amount
+
+	// DefaultFilterMaxAge is the maxage used by name_filter when it is
+	// not given.
+	DefaultFilterMaxAge int
+
+	// PubKeyHashAddrID and ScriptHashAddrID are the version bytes of
+	// base58 P2PKH and P2SH addresses, and Bech32HRP is the human
+	// readable part of segwit addresses.
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+	Bech32HRP        string
+}
+
+// ExpiresIn returns the number of blocks left at height before a name last
+// updated at nameHeight expires, as the expires_in field of name_show.
+func (p *Params) ExpiresIn(nameHeight, height int64) int64 {
+	return nameHeight + p.NameExpirationDepth(height) - height
+}
+
+// IsExpired reports whether a name last updated at nameHeight has expired
+// at height.
+func (p *Params) IsExpired(nameHeight, height int64) bool {
+	return p.ExpiresIn(nameHeight, height) <= 0
+}
+
+// ExpiryHeight returns the first height at which a name last updated at
+// nameHeight is expired.  The expiration depth must not grow by more than one
+// block per block, which holds for every network.
+func (p *Params) ExpiryHeight(nameHeight int64) int64 {
+	// Find a height at which the name is expired, then bisect.
+	lo, hi := nameHeight, nameHeight+1
+	for !p.IsExpired(nameHeight, hi) {
+		lo, hi = hi, nameHeight+2*(hi-nameHeight)
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if p.IsExpired(nameHeight, mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi
+}
+
+// mainNetExpirationDepth is the expiration depth of mainnet and testnet.  It
+// was raised from 12000 to 36000 blocks between heights 24000 and 48000.
+func mainNetExpirationDepth(height int64) int64 {
+	switch {
+	case height < 24000:
+		return 12000
+	case height < 48000:
+		return height - 12000
+	}
+	return 36000
+}
+
+// MainNetParams defines the Namecoin main network.
+var MainNetParams = Params{
+	Name:                "mainnet",
+	MinFirstUpdateDepth: 12,
+	NameExpirationDepth: mainNetExpirationDepth,
+	NameLockedAmount:    btcutil.Amount(1000000),
+	DefaultFilterMaxAge: 36000,
+	PubKeyHashAddrID:    0x34,
+	ScriptHashAddrID:    0x0d,
+	Bech32HRP:           "nc",
+}
+
+// TestNet3Params defines the Namecoin test network.  Its names follow the
+// rules of mainnet.
+var TestNet3Params = Params{
+	Name:                "testnet",
+	MinFirstUpdateDepth: 12,
+	NameExpirationDepth: mainNetExpirationDepth,
+	NameLockedAmount:    btcutil.Amount(1000000),
+	DefaultFilterMaxAge: 36000,
+	PubKeyHashAddrID:    0x6f,
+	ScriptHashAddrID:    0xc4,
+	Bech32HRP:           "tn",
+}
+
+// RegTestParams defines the Namecoin regression test network, whose names
+// expire after 30 blocks.
+var RegTestParams = Params{
+	Name:                "regtest",
+	MinFirstUpdateDepth: 12,
+	NameExpirationDepth: func(int64) int64 { return 30 },
+	NameLockedAmount:    btcutil.Amount(1000000),
+	DefaultFilterMaxAge: 36000,
+	PubKeyHashAddrID:    0x6f,
+	ScriptHashAddrID:    0xc4,
+	Bech32HRP:           "ncrt",
+}
+
+// networks are the params which can be looked up by name with
+// NetworkParams.
+var networks = []*Params{&MainNetParams, &TestNet3Params, &RegTestParams}
+
+// NetworkParams returns the params of the network "mainnet", "testnet" or
+// "regtest".
+func NetworkParams(name string) (*Params, error) {
+	for _, p := range networks {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown network %q", name)
+}
@@ -0,0 +1,46 @@
+package nmcjson
+
+import "testing"
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     *Params
+		nameHeight int64
+		height     int64
+		expiresIn  int64
+		expiryAt   int64
+	}{
+		{name: "mainnet early", params: &MainNetParams, nameHeight: 100, height: 200, expiresIn: 11900, expiryAt: 12100},
+		{name: "mainnet during the raise", params: &MainNetParams, nameHeight: 20000, height: 30000, expiresIn: 8000, expiryAt: 56000},
+		{name: "mainnet", params: &MainNetParams, nameHeight: 500000, height: 500010, expiresIn: 35990, expiryAt: 536000},
+		{name: "testnet", params: &TestNet3Params, nameHeight: 500000, height: 500000, expiresIn: 36000, expiryAt: 536000},
+		{name: "regtest", params: &RegTestParams, nameHeight: 10, height: 25, expiresIn: 15, expiryAt: 40},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.params.ExpiresIn(test.nameHeight, test.height); got != test.expiresIn {
+				t.Fatalf("ExpiresIn: got %d, want %d", got, test.expiresIn)
+			}
+			at := test.params.ExpiryHeight(test.nameHeight)
+			if at != test.expiryAt {
+				t.Fatalf("ExpiryHeight: got %d, want %d", at, test.expiryAt)
+			}
+			if test.params.IsExpired(test.nameHeight, at-1) || !test.params.IsExpired(test.nameHeight, at) {
+				t.Fatalf("IsExpired does not change at %d", at)
+			}
+		})
+	}
+}
+
+func TestNetworkParams(t *testing.T) {
+	for _, want := range []*Params{&MainNetParams, &TestNet3Params, &RegTestParams} {
+		got, err := NetworkParams(want.Name)
+		if err != nil || got != want {
+			t.Fatalf("%s: got %v %v, want %v", want.Name, got, err, want)
+		}
+	}
+	if _, err := NetworkParams("simnet"); err == nil {
+		t.Fatal("simnet: got no error")
+	}
+}
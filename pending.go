@@ -0,0 +1,87 @@
+package nmcjson
+
+// Name operations reported in the op field of NamePendingResult.
+const (
+	OpNameNew         = "name_new"
+	OpNameFirstUpdate = "name_firstupdate"
+	OpNameUpdate      = "name_update"
+)
+
+// latestPending returns the last pending name_firstupdate or name_update of
+// each name in pending.  name_new operations are ignored since they do not
+// reveal the name.
+func latestPending(pending []NamePendingResult) map[string]NamePendingResult {
+	latest := make(map[string]NamePendingResult)
+	for _, p := range pending {
+		if p.Op != OpNameFirstUpdate && p.Op != OpNameUpdate {
+			continue
+		}
+		latest[p.Name] = p
+	}
+	return latest
+}
+
+// EffectiveNameShow returns show with the latest pending operation on the
+// same name applied, and whether any pending operation was found.  The Height,
+// ExpiresIn and Expired fields keep their confirmed values.
+//
+// Names are matched as strings, so show and pending must have been requested
+// with the same name encoding.
+func EffectiveNameShow(show NameShowResult, pending []NamePendingResult) (NameShowResult, bool) {
+	p, ok := latestPending(pending)[show.Name]
+	if !ok {
+		return show, false
+	}
+	show.Value = p.Value
+	show.ValueEncoding = p.ValueEncoding
+	show.Txid = p.Txid
+	show.Vout = p.Vout
+	show.Address = p.Address
+	return show, true
+}
+
+// EffectiveNameList returns list with the latest pending operation on each
+// name applied.  Pending operations which send a name out of the wallet mark
+// it as transferred, and pending registrations of names which are not yet in
+// list but belong to the wallet are appended.  The Height, ExpiresIn and
+// Expired fields keep their confirmed values.
+//
+// Names are matched as strings, so list and pending must have been requested
+// with the same name encoding.
+func EffectiveNameList(list []NameListResult, pending []NamePendingResult) []NameListResult {
+	latest := latestPending(pending)
+	effective := make([]NameListResult, 0, len(list))
+	for _, entry := range list {
+		if p, ok := latest[entry.Name]; ok {
+			entry.Value = p.Value
+			entry.ValueEncoding = p.ValueEncoding
+			entry.Txid = p.Txid
+			entry.Vout = p.Vout
+			entry.Address = p.Address
+			entry.Transferred = !p.IsMine
+			delete(latest, entry.Name)
+		}
+		effective = append(effective, entry)
+	}
+
+	// Keep the order of pending for the names which are new to the list.
+	for _, p := range pending {
+		q, ok := latest[p.Name]
+		if !ok || q.Txid != p.Txid || !p.IsMine {
+			continue
+		}
+		effective = append(effective, NameListResult{
+			NameValue: NameValue{
+				Name:          p.Name,
+				Value:         p.Value,
+				NameEncoding:  p.NameEncoding,
+				ValueEncoding: p.ValueEncoding,
+			},
+			Txid:    p.Txid,
+			Vout:    p.Vout,
+			Address: p.Address,
+		})
+		delete(latest, p.Name)
+	}
+	return effective
+}
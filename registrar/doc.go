@@ -0,0 +1,24 @@
+/*
+Package registrar drives Namecoin name registrations through name_new and
+name_firstupdate.
+
+A Registrar stores each registration in a Store before every step that
+talks to namecoind.  The rand of a name_new is generated and stored before
+the name_new is sent, so it is never held only in memory or only by the
+node.  Poll, or Run in a loop, compares the block height with the height
+at which name_new was sent and sends name_firstupdate once it is valid.
+
+# Crash recovery
+
+Registrations are resumed from the Store by New.  A registration left in
+StateNew has its name_new sent again with the stored rand, which at worst
+wastes one reservation.
+A registration left in StateFirstUpdating is checked with name_show and
+name_pending first, and name_firstupdate is only sent again when neither
+shows it.  The same check settles a send which failed without a reply from
+namecoind, such as a timeout, since the node may have accepted it, and a
+resend rejected because the name is active or has a pending operation.  A
+registration only fails as taken once the registered name is confirmed not
+to be its own.
+*/
+package registrar
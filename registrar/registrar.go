@@ -0,0 +1,551 @@
+package registrar
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kefkius/nmcjson"
+)
+
+// DefaultPollInterval is how often Run polls the block height.
+const DefaultPollInterval = time.Minute
+
+// State is the progress of a registration.
+type State int
+
+const (
+	// StateNew means the registration and its rand were stored but no
+	// name_new is known to have been sent.
+	StateNew State = iota
+
+	// StateWaiting means name_new was sent and the registration is waiting
+	// for the name_new to be deep enough.
+	StateWaiting
+
+	// StateFirstUpdating means name_firstupdate is being sent, or was
+	// sent without a clear reply, or is pending in the mempool.  The
+	// registrar checks the chain and the mempool before sending it again.
+	StateFirstUpdating
+
+	// StateDone means name_firstupdate was sent successfully.
+	StateDone
+
+	// StateFailed means the registration can not complete.
+	StateFailed
+)
+
+// Map of State values back to their names for pretty printing.
+var stateStrings = map[State]string{
+	StateNew:           "new",
+	StateWaiting:       "waiting",
+	StateFirstUpdating: "firstupdating",
+	StateDone:          "done",
+	StateFailed:        "failed",
+}
+
+// String returns the State in human-readable form.
+func (s State) String() string {
+	if str, ok := stateStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("unknown state (%d)", int(s))
+}
+
+// Registration is a persisted name registration.
+type Registration struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	ToAddress string `json:"toaddress,omitempty"`
+
+	// Txid is the txid of the name_new.  Rand is its salt, which is
+	// generated and stored before the name_new is sent.
+	Txid string `json:"txid,omitempty"`
+	Rand string `json:"rand,omitempty"`
+
+	// Commitment is the hex encoded hash committed to by the name_new,
+	// computed from Name and Rand.  It is checked again before
+	// name_firstupdate to catch corrupted records.
+	Commitment string `json:"commitment,omitempty"`
+
+	// NewHeight is the block height at which name_new was sent.
+	NewHeight int64 `json:"newheight,omitempty"`
+
+	// FirstUpdateTxid is the txid of the name_firstupdate once sent.
+	FirstUpdateTxid string `json:"firstupdatetxid,omitempty"`
+
+	State     State     `json:"state"`
+	LastError string    `json:"lasterror,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Node is the subset of nmcjson.Client used by the registrar.
+type Node interface {
+	GetBlockCount(ctx context.Context) (int64, error)
+	NameNewWithOptions(ctx context.Context, name string, options *nmcjson.NameTxOptions) (*nmcjson.NameNewResult, error)
+	NameFirstUpdate(ctx context.Context, name, rand, txid, value, toAddress string) (nmcjson.NameFirstUpdateResult, error)
+	NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error)
+	NamePending(ctx context.Context, name string) ([]nmcjson.NamePendingResult, error)
+}
+
+// Enforce that nmcjson.Client satisfies the Node interface.
+var _ Node = (*nmcjson.Client)(nil)
+
+// Config is the configuration of a Registrar.
+type Config struct {
+	// Node is the namecoind connection used to send commands.
+	Node Node
+
+	// Store persists registrations.
+	Store Store
+
+	// Params are the params of the network of Node.  Nil means
+	// nmcjson.MainNetParams.
+	Params *nmcjson.Params
+
+	// MinDepth is the number of blocks to wait after name_new.  Zero
+	// means the MinFirstUpdateDepth of Params.
+	MinDepth int64
+
+	// PollInterval is how often Run polls the block height.  Zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+
+	// Notify, when not nil, is called with a copy of a registration every
+	// time it changes.
+	Notify func(Registration)
+}
+
+// ErrAlreadyRegistering is returned by Register when the name already has a
+// registration in progress.
+var ErrAlreadyRegistering = errors.New("name is already being registered")
+
+// errForgotten is returned by save for a registration forgotten while it was
+// claimed, so that no further commands are sent for it.
+var errForgotten = errors.New("registration was forgotten")
+
+// Registrar drives name registrations from name_new to name_firstupdate,
+// persisting every step so that rands survive restarts.
+type Registrar struct {
+	cfg  Config
+	mtx  sync.Mutex
+	regs map[string]*Registration
+
+	// busy holds the names of the registrations claimed by a Register or
+	// Poll which is talking to the node, so no other one sends commands
+	// for them at the same time.
+	busy map[string]bool
+}
+
+// New returns a Registrar which resumes the registrations found in
+// cfg.Store.
+func New(cfg *Config) (*Registrar, error) {
+	if cfg.Node == nil || cfg.Store == nil {
+		return nil, errors.New("registrar: node and store are required")
+	}
+	r := &Registrar{
+		cfg:  *cfg,
+		regs: make(map[string]*Registration),
+		busy: make(map[string]bool),
+	}
+	if r.cfg.Params == nil {
+		r.cfg.Params = &nmcjson.MainNetParams
+	}
+	if r.cfg.MinDepth == 0 {
+		r.cfg.MinDepth = r.cfg.Params.MinFirstUpdateDepth
+	}
+	if r.cfg.PollInterval == 0 {
+		r.cfg.PollInterval = DefaultPollInterval
+	}
+	regs, err := r.cfg.Store.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, reg := range regs {
+		r.regs[reg.Name] = reg
+	}
+	return r, nil
+}
+
+// Register starts registering name with value.  The registration is stored
+// with a new rand before name_new is sent, and the name_new result is stored
+// as soon as it is received.  toAddress may be empty to keep the name in the
+// wallet.
+//
+// The name, value and toAddress are validated before anything is stored, so
+// no name_new is paid for a registration whose name_firstupdate would be
+// rejected.  If sending name_new fails the registration is kept in StateNew
+// and name_new is retried with the same rand by the next Poll.
+func (r *Registrar) Register(ctx context.Context, name, value, toAddress string) (*Registration, error) {
+	check := &nmcjson.NameUpdateCmd{Name: name, Value: value}
+	if toAddress != "" {
+		check.Options = &nmcjson.NameTxOptions{DestAddress: toAddress}
+	}
+	if err := check.Validate(r.cfg.Params); err != nil {
+		return nil, err
+	}
+
+	reg := &Registration{
+		Name:      name,
+		Value:     value,
+		ToAddress: toAddress,
+		State:     StateNew,
+	}
+	if err := newRand(reg); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	if prev, ok := r.regs[name]; r.busy[name] || ok && prev.State != StateDone && prev.State != StateFailed {
+		r.mtx.Unlock()
+		return nil, ErrAlreadyRegistering
+	}
+	if err := r.saveLocked(reg); err != nil {
+		r.mtx.Unlock()
+		return nil, err
+	}
+	r.busy[name] = true
+	r.mtx.Unlock()
+	defer r.release(name)
+
+	if err := r.sendNameNew(ctx, reg); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// newRand gives reg a new rand and its commitment.
+func newRand(reg *Registration) error {
+	rand, err := nmcjson.NewRand()
+	if err != nil {
+		return err
+	}
+	return setRand(reg, rand)
+}
+
+// setRand sets the rand of reg and the commitment computed from it.
+func setRand(reg *Registration, rand string) error {
+	commitment, err := nmcjson.NameCommitment(reg.Name, rand)
+	if err != nil {
+		return err
+	}
+	reg.Rand = rand
+	reg.Commitment = hex.EncodeToString(commitment)
+	return nil
+}
+
+// sendNameNew sends the name_new of reg with its stored rand and stores the
+// result.  Since the rand is stored first, a name_new whose reply was lost
+// commits to the same rand as the one resent by the next poll, and no rand
+// is only known to the node.  The caller must have claimed reg.
+func (r *Registrar) sendNameNew(ctx context.Context, reg *Registration) error {
+	if reg.Rand == "" {
+		// Registrations stored before rands were generated here.
+		if err := newRand(reg); err != nil {
+			return err
+		}
+		if err := r.save(reg); err != nil {
+			return err
+		}
+	}
+	height, err := r.cfg.Node.GetBlockCount(ctx)
+	if err != nil {
+		return err
+	}
+	options := &nmcjson.NameTxOptions{Rand: reg.Rand}
+	res, err := r.cfg.Node.NameNewWithOptions(ctx, reg.Name, options)
+	if err == nil && res.Rand != reg.Rand {
+		// A node which ignores the rand option commits to its own.
+		err = setRand(reg, res.Rand)
+	}
+	if err != nil {
+		reg.LastError = err.Error()
+		r.save(reg)
+		return err
+	}
+	reg.Txid = res.Txid
+	reg.NewHeight = height
+	reg.State = StateWaiting
+	reg.LastError = ""
+	return r.save(reg)
+}
+
+// Poll checks the block height once and advances every registration which
+// can make progress.  Errors from individual registrations are recorded in
+// their LastError and retried on the next poll.  The registrations are not
+// locked while commands are sent, and ones claimed by a concurrent Register
+// or Poll are skipped.
+func (r *Registrar) Poll(ctx context.Context) error {
+	height, err := r.cfg.Node.GetBlockCount(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.mtx.Lock()
+	names := make([]string, 0, len(r.regs))
+	for name, reg := range r.regs {
+		if reg.State != StateDone && reg.State != StateFailed {
+			names = append(names, name)
+		}
+	}
+	r.mtx.Unlock()
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		reg := r.claim(name)
+		if reg == nil {
+			continue
+		}
+		switch reg.State {
+		case StateNew:
+			r.sendNameNew(ctx, reg)
+		case StateWaiting:
+			if height-reg.NewHeight >= r.cfg.MinDepth {
+				r.sendFirstUpdate(ctx, reg)
+			}
+		case StateFirstUpdating:
+			r.recoverFirstUpdate(ctx, reg)
+		}
+		r.release(name)
+	}
+	return nil
+}
+
+// claim marks the registration of name as busy and returns a copy of it for
+// the caller to advance without holding mtx.  It returns nil if the
+// registration is gone or already claimed.  The caller must release name
+// when done.
+func (r *Registrar) claim(name string) *Registration {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	reg, ok := r.regs[name]
+	if !ok || r.busy[name] {
+		return nil
+	}
+	r.busy[name] = true
+	regCopy := *reg
+	return &regCopy
+}
+
+// release ends the claim on the registration of name.
+func (r *Registrar) release(name string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.busy, name)
+}
+
+// sendFirstUpdate sends the name_firstupdate of reg.  The registration is
+// moved to StateFirstUpdating before sending, so a crash while sending is
+// detected on restart.  A registration whose rand does not match its stored
+// commitment is failed without sending.  The caller must have claimed reg.
+func (r *Registrar) sendFirstUpdate(ctx context.Context, reg *Registration) {
+	if err := checkCommitment(reg); err != nil {
+		reg.State = StateFailed
+		reg.LastError = err.Error()
+		r.save(reg)
+		return
+	}
+	reg.State = StateFirstUpdating
+	if err := r.save(reg); err != nil {
+		reg.State = StateWaiting
+		return
+	}
+	txid, err := r.cfg.Node.NameFirstUpdate(ctx, reg.Name, reg.Rand, reg.Txid, reg.Value, reg.ToAddress)
+	if err == nil {
+		reg.FirstUpdateTxid = string(txid)
+		reg.State = StateDone
+		reg.LastError = ""
+		r.save(reg)
+		return
+	}
+	reg.LastError = err.Error()
+
+	var rpcErr *nmcjson.RPCError
+	if !errors.As(err, &rpcErr) {
+		// The node may have accepted the name_firstupdate before the
+		// reply was lost, so the registration stays in
+		// StateFirstUpdating and is settled by the next poll instead
+		// of being sent again blindly.
+		r.save(reg)
+		return
+	}
+	if errors.Is(err, nmcjson.ErrNameAlreadyRegistered) || errors.Is(err, nmcjson.ErrNamePending) {
+		// Both are also the reply to a resent name_firstupdate whose
+		// first attempt went through.
+		found, err := r.settle(ctx, reg)
+		switch {
+		case err != nil:
+			// Settled by the next poll.
+			r.save(reg)
+			return
+		case found:
+			return
+		}
+	}
+	// Someone else owns the name, so retrying can not succeed.  Any
+	// other rejection, including ErrFirstUpdateTooEarly when the name_new
+	// confirmed late, is retried on the next poll.
+	reg.State = StateWaiting
+	if errors.Is(err, nmcjson.ErrNameAlreadyRegistered) {
+		reg.State = StateFailed
+	}
+	r.save(reg)
+}
+
+// checkCommitment verifies the rand of reg against its commitment.  Records
+// stored before commitments were kept are not checked.
+func checkCommitment(reg *Registration) error {
+	if reg.Commitment == "" {
+		return nil
+	}
+	commitment, err := hex.DecodeString(reg.Commitment)
+	if err != nil {
+		return err
+	}
+	return nmcjson.VerifyNameCommitment(reg.Name, reg.Rand, commitment)
+}
+
+// recoverFirstUpdate resolves a registration left in StateFirstUpdating by a
+// crash or a failed send.  If the name_firstupdate of the registration is
+// found on the chain or in the mempool it is not sent again; otherwise it is.
+// The caller must have claimed reg.
+func (r *Registrar) recoverFirstUpdate(ctx context.Context, reg *Registration) {
+	found, err := r.settle(ctx, reg)
+	if err != nil {
+		reg.LastError = err.Error()
+		r.save(reg)
+		return
+	}
+	if !found {
+		r.sendFirstUpdate(ctx, reg)
+	}
+}
+
+// settle looks for the name_firstupdate of reg, in case an earlier attempt
+// was accepted.  A confirmed one completes the registration, and a pending
+// one of the wallet leaves it in StateFirstUpdating until it confirms.  It
+// reports whether either was found.  The caller must have claimed reg.
+func (r *Registrar) settle(ctx context.Context, reg *Registration) (bool, error) {
+	show, err := r.cfg.Node.NameShow(ctx, reg.Name)
+	switch {
+	case err == nil && isOwn(reg, show):
+		reg.FirstUpdateTxid = show.Txid
+		reg.State = StateDone
+		reg.LastError = ""
+		r.save(reg)
+		return true, nil
+	case err != nil && !errors.Is(err, nmcjson.ErrNameNotFound):
+		return false, err
+	}
+	pending, err := r.cfg.Node.NamePending(ctx, reg.Name)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pending {
+		if p.Op == nmcjson.OpNameFirstUpdate && p.IsMine && p.Value == reg.Value {
+			reg.FirstUpdateTxid = p.Txid
+			reg.State = StateFirstUpdating
+			reg.LastError = ""
+			r.save(reg)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isOwn reports whether show is the name_firstupdate of reg: the txid seen
+// pending earlier, or a registration after the name_new setting the value
+// of reg and sending the name where reg asked.
+func isOwn(reg *Registration, show *nmcjson.NameShowResult) bool {
+	if show.Expired {
+		return false
+	}
+	if reg.FirstUpdateTxid != "" && show.Txid == reg.FirstUpdateTxid {
+		return true
+	}
+	return show.Height > reg.NewHeight && show.Value == reg.Value &&
+		(reg.ToAddress == "" || show.Address == reg.ToAddress)
+}
+
+// Run polls the block height every PollInterval until ctx is done.
+func (r *Registrar) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		r.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns a copy of the registration of name.
+func (r *Registrar) Status(name string) (Registration, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	reg, ok := r.regs[name]
+	if !ok {
+		return Registration{}, false
+	}
+	return *reg, true
+}
+
+// Statuses returns a copy of every registration, sorted by name.
+func (r *Registrar) Statuses() []Registration {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	regs := make([]Registration, 0, len(r.regs))
+	for _, reg := range r.regs {
+		regs = append(regs, *reg)
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		return regs[i].Name < regs[j].Name
+	})
+	return regs
+}
+
+// Forget removes the registration of name from the registrar and its store.
+func (r *Registrar) Forget(name string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err := r.cfg.Store.Delete(name); err != nil {
+		return err
+	}
+	delete(r.regs, name)
+	return nil
+}
+
+// save stores a copy of reg, which the caller has claimed, and notifies the
+// listener.  A registration forgotten while it was claimed is not stored
+// again.  The caller must not hold mtx.
+func (r *Registrar) save(reg *Registration) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.regs[reg.Name]; !ok {
+		return errForgotten
+	}
+	return r.saveLocked(reg)
+}
+
+// saveLocked is save for a caller holding mtx, and also stores registrations
+// which are not known yet.
+func (r *Registrar) saveLocked(reg *Registration) error {
+	reg.Updated = time.Now()
+	if err := r.cfg.Store.Put(reg); err != nil {
+		return err
+	}
+	regCopy := *reg
+	r.regs[reg.Name] = &regCopy
+	if r.cfg.Notify != nil {
+		r.cfg.Notify(regCopy)
+	}
+	return nil
+}
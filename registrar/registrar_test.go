@@ -0,0 +1,449 @@
+package registrar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+)
+
+// minDepth is the depth a name_new needs on mainnet.
+var minDepth = nmcjson.MainNetParams.MinFirstUpdateDepth
+
+// errLostReply simulates a connection which fails after the node received
+// the request.
+var errLostReply = errors.New("connection reset")
+
+// memNode is a Node which keeps the names it registers in memory.  Every
+// operation is confirmed at the current height.
+type memNode struct {
+	height int64
+	seq    int
+	news   map[string]memNew
+	names  map[string]*nmcjson.NameShowResult
+
+	// nameNewErr and firstUpdateErr, when not nil, replace the replies
+	// to name_new and name_firstupdate.  The commands are still carried
+	// out when forward is set.
+	nameNewErr     error
+	firstUpdateErr error
+	forward        bool
+
+	// onFirstUpdate, when not nil, is called on every name_firstupdate.
+	onFirstUpdate func()
+
+	// pending is the reply to name_pending.
+	pending []nmcjson.NamePendingResult
+
+	// firstUpdates counts the name_firstupdate commands received.
+	firstUpdates int
+}
+
+// memNew is a name_new sent to a memNode.
+type memNew struct {
+	name, rand string
+	height     int64
+}
+
+func newMemNode() *memNode {
+	return &memNode{
+		news:  make(map[string]memNew),
+		names: make(map[string]*nmcjson.NameShowResult),
+	}
+}
+
+// txid returns a new transaction id.
+func (n *memNode) txid() string {
+	n.seq++
+	return fmt.Sprintf("%064x", n.seq)
+}
+
+func (n *memNode) GetBlockCount(ctx context.Context) (int64, error) {
+	return n.height, nil
+}
+
+func (n *memNode) NameNewWithOptions(ctx context.Context, name string, options *nmcjson.NameTxOptions) (*nmcjson.NameNewResult, error) {
+	if n.nameNewErr != nil && !n.forward {
+		return nil, n.nameNewErr
+	}
+	txid := n.txid()
+	rand := fmt.Sprintf("%040x", n.seq)
+	if options != nil && options.Rand != "" {
+		rand = options.Rand
+	}
+	n.news[txid] = memNew{name: name, rand: rand, height: n.height}
+	if n.nameNewErr != nil {
+		return nil, n.nameNewErr
+	}
+	return &nmcjson.NameNewResult{Txid: txid, Rand: rand}, nil
+}
+
+func (n *memNode) NameFirstUpdate(ctx context.Context, name, rand, txid, value, toAddress string) (nmcjson.NameFirstUpdateResult, error) {
+	n.firstUpdates++
+	if n.onFirstUpdate != nil {
+		n.onFirstUpdate()
+	}
+	if n.firstUpdateErr != nil {
+		if n.forward {
+			n.firstUpdate(name, rand, txid, value, toAddress)
+		}
+		return "", n.firstUpdateErr
+	}
+	return n.firstUpdate(name, rand, txid, value, toAddress)
+}
+
+// firstUpdate registers name if the name_new txid matches.
+func (n *memNode) firstUpdate(name, rand, txid, value, toAddress string) (nmcjson.NameFirstUpdateResult, error) {
+	nn, ok := n.news[txid]
+	switch {
+	case !ok || nn.name != name || nn.rand != rand:
+		return "", nmcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "name_new does not match")
+	case n.height-nn.height < minDepth:
+		return "", nmcjson.NewRPCError(btcjson.ErrRPCWallet, "previous tx used for name_new is not mature for first_update")
+	case n.names[name] != nil:
+		return "", nmcjson.NewRPCError(btcjson.ErrRPCWallet, "this name is already active")
+	}
+	show := &nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{Name: name, Value: value},
+		Txid:      n.txid(),
+		Address:   toAddress,
+		Height:    n.height,
+	}
+	n.names[name] = show
+	return nmcjson.NameFirstUpdateResult(show.Txid), nil
+}
+
+func (n *memNode) NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error) {
+	show, ok := n.names[name]
+	if !ok {
+		return nil, nmcjson.NewRPCError(btcjson.ErrRPCWallet, "name not found")
+	}
+	showCopy := *show
+	return &showCopy, nil
+}
+
+func (n *memNode) NamePending(ctx context.Context, name string) ([]nmcjson.NamePendingResult, error) {
+	return n.pending, nil
+}
+
+// newTestStore returns a FileStore in a temporary directory.
+func newTestStore(t *testing.T) *FileStore {
+	t.Helper()
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "registrations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	node := newMemNode()
+	r, err := New(&Config{Node: node, Store: newTestStore(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := r.Register(ctx, "d/example", "v", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.State != StateWaiting || reg.Rand == "" {
+		t.Fatalf("after register: got state %v rand %q, want %v and a rand", reg.State, reg.Rand, StateWaiting)
+	}
+	if _, err := r.Register(ctx, "d/example", "v", ""); !errors.Is(err, ErrAlreadyRegistering) {
+		t.Fatalf("second register: got %v, want %v", err, ErrAlreadyRegistering)
+	}
+
+	node.height += minDepth - 1
+	r.Poll(ctx)
+	if reg, _ := r.Status("d/example"); reg.State != StateWaiting {
+		t.Fatalf("state after %d blocks: got %v, want %v", minDepth-1, reg.State, StateWaiting)
+	}
+	node.height++
+	r.Poll(ctx)
+	reg2, _ := r.Status("d/example")
+	if reg2.State != StateDone {
+		t.Fatalf("state after %d blocks: got %v (%s), want %v", minDepth, reg2.State, reg2.LastError, StateDone)
+	}
+	if show := node.names["d/example"]; show.Txid != reg2.FirstUpdateTxid || show.Value != "v" {
+		t.Fatalf("name_show: got txid %s value %q, want %s %q", show.Txid, show.Value, reg2.FirstUpdateTxid, "v")
+	}
+}
+
+func TestNameNewRetried(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		forward bool // whether the node carries out the failed name_new
+	}{
+		{"refused", errors.New("connection refused"), false},
+		{"reply lost", errLostReply, true},
+		{"rejected", nmcjson.NewRPCError(btcjson.ErrRPCWallet, "insufficient funds"), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			node := newMemNode()
+			node.nameNewErr, node.forward = test.err, test.forward
+			r, err := New(&Config{Node: node, Store: newTestStore(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Register(ctx, "d/example", "v", ""); err == nil {
+				t.Fatal("register: got no error")
+			}
+			reg, _ := r.Status("d/example")
+			if reg.State != StateNew || reg.LastError == "" || reg.Rand == "" {
+				t.Fatalf("after failed name_new: got %v (%q) rand %q, want %v with an error and a rand",
+					reg.State, reg.LastError, reg.Rand, StateNew)
+			}
+			node.nameNewErr, node.forward = nil, false
+			r.Poll(ctx)
+			reg2, _ := r.Status("d/example")
+			if reg2.State != StateWaiting {
+				t.Fatalf("after poll: got %v (%s), want %v", reg2.State, reg2.LastError, StateWaiting)
+			}
+			// Every name_new the node saw commits to the stored rand.
+			for txid, nn := range node.news {
+				if nn.rand != reg.Rand || reg2.Rand != reg.Rand {
+					t.Fatalf("name_new %s: rand %q, stored %q then %q", txid, nn.rand, reg.Rand, reg2.Rand)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeRand(t *testing.T) {
+	// A node which ignores the rand option commits to its own.
+	ctx := context.Background()
+	node := &ignoreRandNode{newMemNode()}
+	r, err := New(&Config{Node: node, Store: newTestStore(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := r.Register(ctx, "d/example", "v", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nn := node.news[reg.Txid]; nn.rand != reg.Rand {
+		t.Fatalf("rand: got %q, want the node's %q", reg.Rand, nn.rand)
+	}
+	node.height += minDepth
+	r.Poll(ctx)
+	if reg, _ := r.Status("d/example"); reg.State != StateDone {
+		t.Fatalf("after poll: got %v (%s), want %v", reg.State, reg.LastError, StateDone)
+	}
+}
+
+// ignoreRandNode is a memNode which generates its own name_new rands.
+type ignoreRandNode struct {
+	*memNode
+}
+
+func (n *ignoreRandNode) NameNewWithOptions(ctx context.Context, name string, options *nmcjson.NameTxOptions) (*nmcjson.NameNewResult, error) {
+	return n.memNode.NameNewWithOptions(ctx, name, nil)
+}
+
+func TestPollUnlocked(t *testing.T) {
+	// Commands are sent without holding the registrar's lock, so the
+	// registrations can be read meanwhile.
+	ctx := context.Background()
+	node := newMemNode()
+	r, err := New(&Config{Node: node, Store: newTestStore(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Register(ctx, "d/example", "v", ""); err != nil {
+		t.Fatal(err)
+	}
+	var seen State
+	node.onFirstUpdate = func() {
+		reg, _ := r.Status("d/example")
+		seen = reg.State
+	}
+	node.height += minDepth
+	r.Poll(ctx)
+	if seen != StateFirstUpdating {
+		t.Fatalf("state while sending: got %v, want %v", seen, StateFirstUpdating)
+	}
+	if reg, _ := r.Status("d/example"); reg.State != StateDone {
+		t.Fatalf("after poll: got %v (%s), want %v", reg.State, reg.LastError, StateDone)
+	}
+}
+
+func TestFirstUpdateSettle(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		forward   bool
+		pending   bool
+		otherWins bool
+		state     State // after the failed send
+		settled   State // after the next poll
+	}{{
+		name:    "reply lost after acceptance",
+		err:     errLostReply,
+		forward: true,
+		state:   StateFirstUpdating,
+		settled: StateDone,
+	}, {
+		name:    "request lost",
+		err:     errLostReply,
+		state:   StateFirstUpdating,
+		settled: StateDone,
+	}, {
+		name: "own operation pending",
+		err: nmcjson.NewRPCError(btcjson.ErrRPCTxError,
+			"there are pending operations on that name"),
+		pending: true,
+		state:   StateFirstUpdating,
+		settled: StateFirstUpdating,
+	}, {
+		name: "own resend rejected as active",
+		err: nmcjson.NewRPCError(btcjson.ErrRPCWallet,
+			"this name is already active"),
+		forward: true,
+		state:   StateDone,
+		settled: StateDone,
+	}, {
+		name: "registered by someone else",
+		err: nmcjson.NewRPCError(btcjson.ErrRPCWallet,
+			"this name is already active"),
+		otherWins: true,
+		state:     StateFailed,
+		settled:   StateFailed,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			node := newMemNode()
+			r, err := New(&Config{Node: node, Store: newTestStore(t)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.otherWins {
+				// Register the name first with another value.
+				nn, _ := node.NameNewWithOptions(ctx, "d/example", nil)
+				node.height += minDepth
+				if _, err := node.NameFirstUpdate(ctx, "d/example", nn.Rand, nn.Txid, "other", ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := r.Register(ctx, "d/example", "v", ""); err != nil {
+				t.Fatal(err)
+			}
+			node.height += minDepth
+			node.firstUpdateErr, node.forward = test.err, test.forward
+			if test.pending {
+				reg, _ := r.Status("d/example")
+				node.pending = []nmcjson.NamePendingResult{{
+					NameValue: nmcjson.NameValue{Name: "d/example", Value: "v"},
+					Op:        nmcjson.OpNameFirstUpdate,
+					Txid:      reg.Txid,
+					IsMine:    true,
+				}}
+			}
+			r.Poll(ctx)
+			if reg, _ := r.Status("d/example"); reg.State != test.state {
+				t.Fatalf("after send: got %v (%s), want %v", reg.State, reg.LastError, test.state)
+			}
+
+			node.firstUpdateErr, node.forward = nil, false
+			r.Poll(ctx)
+			reg, _ := r.Status("d/example")
+			if reg.State != test.settled {
+				t.Fatalf("after poll: got %v (%s), want %v", reg.State, reg.LastError, test.settled)
+			}
+			if reg.State == StateDone {
+				show := node.names["d/example"]
+				if show.Value != "v" || show.Txid != reg.FirstUpdateTxid {
+					t.Fatalf("name_show: got %+v, want value %q txid %s", show, "v", reg.FirstUpdateTxid)
+				}
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name         string
+		state        State
+		registered   bool // whether the name_firstupdate is on the chain
+		state2       State
+		firstUpdates int
+	}{
+		{"new", StateNew, false, StateWaiting, 0},
+		{"waiting", StateWaiting, false, StateDone, 1},
+		{"firstupdating, sent", StateFirstUpdating, true, StateDone, 0},
+		{"firstupdating, not sent", StateFirstUpdating, false, StateDone, 1},
+		{"done", StateDone, true, StateDone, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			node := newMemNode()
+			store := newTestStore(t)
+			reg := &Registration{Name: "d/example", Value: "v", State: test.state}
+			if test.state != StateNew {
+				nn, _ := node.NameNewWithOptions(ctx, reg.Name, nil)
+				reg.Txid, reg.Rand = nn.Txid, nn.Rand
+			}
+			node.height += minDepth
+			if test.registered {
+				node.NameFirstUpdate(ctx, reg.Name, reg.Rand, reg.Txid, reg.Value, "")
+				node.firstUpdates = 0
+			}
+			if err := store.Put(reg); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := New(&Config{Node: node, Store: store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Poll(ctx)
+			got, _ := r.Status("d/example")
+			if got.State != test.state2 {
+				t.Fatalf("state: got %v (%s), want %v", got.State, got.LastError, test.state2)
+			}
+			if node.firstUpdates != test.firstUpdates {
+				t.Fatalf("name_firstupdate sent %d times, want %d", node.firstUpdates, test.firstUpdates)
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registrations.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Registration{Name: "d/a", Value: "1", Rand: "00ff", State: StateWaiting}
+	b := &Registration{Name: "d/b", Value: "2", State: StateNew}
+	for _, reg := range []*Registration{a, b} {
+		if err := store.Put(reg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete("d/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := reopened.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || !reflect.DeepEqual(*all[0], *a) {
+		t.Fatalf("got %+v, want only %+v", all, a)
+	}
+}
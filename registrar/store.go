@@ -0,0 +1,190 @@
+package registrar
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists registrations.  Implementations must make Put durable
+// before returning, since the registrar relies on it to keep rands safe
+// across restarts.
+type Store interface {
+	// Put inserts or replaces the registration of reg.Name.
+	Put(reg *Registration) error
+
+	// Delete removes the registration of name.
+	Delete(name string) error
+
+	// All returns every stored registration.
+	All() ([]*Registration, error)
+}
+
+// KV is the subset of an embedded key-value database used by KVStore.
+// Adapters for databases such as bbolt or leveldb only need to implement
+// these three methods.
+type KV interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	ForEach(fn func(key, value []byte) error) error
+}
+
+// KVStore is a Store backed by an embedded key-value database.
+type KVStore struct {
+	kv KV
+}
+
+// Enforce that KVStore satisfies the Store interface.
+var _ Store = (*KVStore)(nil)
+
+// NewKVStore returns a Store which keeps registrations in kv, keyed by name.
+func NewKVStore(kv KV) *KVStore {
+	return &KVStore{kv: kv}
+}
+
+// Put inserts or replaces the registration of reg.Name.  Part of the Store
+// interface.
+func (s *KVStore) Put(reg *Registration) error {
+	b, err := json.Marshal(reg)
+	if err != nil {
+		return err
+	}
+	return s.kv.Put([]byte(reg.Name), b)
+}
+
+// Delete removes the registration of name.  Part of the Store interface.
+func (s *KVStore) Delete(name string) error {
+	return s.kv.Delete([]byte(name))
+}
+
+// All returns every stored registration.  Part of the Store interface.
+func (s *KVStore) All() ([]*Registration, error) {
+	var regs []*Registration
+	err := s.kv.ForEach(func(key, value []byte) error {
+		var reg Registration
+		if err := json.Unmarshal(value, &reg); err != nil {
+			return err
+		}
+		regs = append(regs, &reg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return regs, nil
+}
+
+// FileStore is a Store which keeps all registrations in a single JSON file.
+// The file is replaced atomically on every change, so a crash leaves either
+// the old or the new contents on disk.
+type FileStore struct {
+	mtx  sync.Mutex
+	path string
+	regs map[string]*Registration
+}
+
+// Enforce that FileStore satisfies the Store interface.
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore opens the store at path, creating it on the first write if it
+// does not exist.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path: path,
+		regs: make(map[string]*Registration),
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.regs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Put inserts or replaces the registration of reg.Name.  Part of the Store
+// interface.
+func (s *FileStore) Put(reg *Registration) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	regCopy := *reg
+	prev, ok := s.regs[reg.Name]
+	s.regs[reg.Name] = &regCopy
+	if err := s.flush(); err != nil {
+		if ok {
+			s.regs[reg.Name] = prev
+		} else {
+			delete(s.regs, reg.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the registration of name.  Part of the Store interface.
+func (s *FileStore) Delete(name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	prev, ok := s.regs[name]
+	if !ok {
+		return nil
+	}
+	delete(s.regs, name)
+	if err := s.flush(); err != nil {
+		s.regs[name] = prev
+		return err
+	}
+	return nil
+}
+
+// All returns every stored registration.  Part of the Store interface.
+func (s *FileStore) All() ([]*Registration, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	regs := make([]*Registration, 0, len(s.regs))
+	for _, reg := range s.regs {
+		regCopy := *reg
+		regs = append(regs, &regCopy)
+	}
+	return regs, nil
+}
+
+// flush atomically writes all registrations to the file.  The caller must
+// hold mtx.
+func (s *FileStore) flush() error {
+	b, err := json.MarshalIndent(s.regs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	// Make the rename itself durable.
+	dir, err := os.Open(filepath.Dir(s.path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,24 @@
+/*
+Package renewer keeps the names of a wallet from expiring.
+
+A name expires unless it is updated before its expiration depth runs out.
+Poll, or Run in a loop, reads the wallet's names with name_list and renews
+each one whose expires_in is below Config.Threshold by sending a
+name_update with its current value.  The names expiring first are renewed
+first.
+
+A name is skipped, and reported as such, when it has already expired,
+when it was transferred out of the wallet, when it matches one of the
+Config.Exclude patterns, when an operation on it is already pending, or
+when name_list could not encode it.  Names which are not due yet are not
+reported.
+
+Renewals are rate limited: at most Config.MaxPerPoll names are renewed per
+poll, the others are left for the next one, and Config.Spacing separates
+consecutive name_update commands.  With Config.DryRun set, nothing is
+sent and the Report lists the names which would have been renewed.
+
+Run polls in a loop, passing every Report to Config.Notify and the errors
+of polls which could not list the names to Config.OnError.
+*/
+package renewer
@@ -0,0 +1,282 @@
+package renewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kefkius/nmcjson"
+)
+
+const (
+	// DefaultThreshold is the expires_in below which names are renewed
+	// when Config.Threshold is zero, about four weeks of blocks.
+	DefaultThreshold = 4032
+
+	// DefaultPollInterval is how often Run polls name_list.
+	DefaultPollInterval = time.Hour
+)
+
+// SkipReason is why a name due for renewal was not renewed.
+type SkipReason string
+
+// Reasons for skipping a name.
+const (
+	SkipExpired     SkipReason = "expired"
+	SkipTransferred SkipReason = "transferred"
+	SkipExcluded    SkipReason = "excluded"
+	SkipPending     SkipReason = "operation pending"
+	SkipEncoding    SkipReason = "name or value not encodable"
+	SkipRateLimited SkipReason = "rate limited"
+)
+
+// Node is the subset of nmcjson.Client used by the renewer.
+type Node interface {
+	NameList(ctx context.Context, name string) ([]nmcjson.NameListResult, error)
+	NamePending(ctx context.Context, name string) ([]nmcjson.NamePendingResult, error)
+	NameUpdate(ctx context.Context, name, value, toAddress string) (nmcjson.NameUpdateResult, error)
+}
+
+// Enforce that nmcjson.Client satisfies the Node interface.
+var _ Node = (*nmcjson.Client)(nil)
+
+// Config is the configuration of a Renewer.
+type Config struct {
+	// Node is the namecoind connection whose wallet names are renewed.
+	Node Node
+
+	// Threshold is the expires_in, in blocks, below which a name is
+	// renewed.  Zero means DefaultThreshold.
+	Threshold int64
+
+	// Exclude are patterns of names which are never renewed.  A name is
+	// excluded if any pattern matches part of it, so anchor patterns to
+	// match whole names.
+	Exclude []*regexp.Regexp
+
+	// MaxPerPoll is the maximum number of names renewed by one poll.  Zero
+	// means no limit.
+	MaxPerPoll int
+
+	// Spacing is the minimum time between two name_update commands.
+	Spacing time.Duration
+
+	// DryRun reports the names which would be renewed without sending
+	// any name_update.
+	DryRun bool
+
+	// PollInterval is how often Run polls name_list.  Zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+
+	// Notify, when not nil, is called by Run with the report of every
+	// poll.
+	Notify func(*Report)
+
+	// OnError, when not nil, is called by Run with every error of a
+	// poll, such as a failure to list the names.
+	OnError func(error)
+}
+
+// Entry is a name in a Report.
+type Entry struct {
+	Name      string
+	ExpiresIn int64
+
+	// Txid is the name_update of a renewed name.  It is empty in a dry
+	// run.
+	Txid string
+
+	// Reason is why a skipped name was not renewed.
+	Reason SkipReason
+
+	// Err is why the renewal of a failed name failed.
+	Err error
+}
+
+// Report is the outcome of a poll.  Names appear in the order they were
+// considered, the most urgent first.
+type Report struct {
+	Time    time.Time
+	DryRun  bool
+	Renewed []Entry
+	Skipped []Entry
+	Failed  []Entry
+}
+
+// String returns a one line summary of r.
+func (r *Report) String() string {
+	verb := "renewed"
+	if r.DryRun {
+		verb = "would renew"
+	}
+	return fmt.Sprintf("%s %d, skipped %d, failed %d",
+		verb, len(r.Renewed), len(r.Skipped), len(r.Failed))
+}
+
+// Renewer renews the names of a wallet before they expire.
+type Renewer struct {
+	cfg Config
+
+	mtx      sync.Mutex // serialises Poll
+	lastSent time.Time
+
+	reportMtx sync.Mutex
+	last      *Report
+}
+
+// New returns a Renewer using cfg.
+func New(cfg *Config) (*Renewer, error) {
+	if cfg.Node == nil {
+		return nil, errors.New("renewer: node is required")
+	}
+	r := &Renewer{cfg: *cfg}
+	if r.cfg.Threshold == 0 {
+		r.cfg.Threshold = DefaultThreshold
+	}
+	if r.cfg.PollInterval == 0 {
+		r.cfg.PollInterval = DefaultPollInterval
+	}
+	return r, nil
+}
+
+// Poll lists the wallet's names and renews those which are due.  The
+// report is returned even when Poll stops early because ctx is done; an
+// error listing the names returns no report.
+func (r *Renewer) Poll(ctx context.Context) (*Report, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	names, err := r.cfg.Node.NameList(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	pending, err := r.cfg.Node.NamePending(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	isPending := make(map[string]bool, len(pending))
+	for _, p := range pending {
+		isPending[p.Name] = true
+	}
+
+	var due []nmcjson.NameListResult
+	for _, n := range names {
+		if n.ExpiresIn < r.cfg.Threshold {
+			due = append(due, n)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].ExpiresIn < due[j].ExpiresIn
+	})
+
+	report := &Report{Time: time.Now(), DryRun: r.cfg.DryRun}
+	defer r.setLast(report)
+	for _, n := range due {
+		entry := Entry{Name: n.Name, ExpiresIn: n.ExpiresIn}
+		if reason, skip := r.skipReason(n, isPending); skip {
+			entry.Reason = reason
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+		if r.cfg.MaxPerPoll > 0 && len(report.Renewed)+len(report.Failed) >= r.cfg.MaxPerPoll {
+			entry.Reason = SkipRateLimited
+			report.Skipped = append(report.Skipped, entry)
+			continue
+		}
+		if r.cfg.DryRun {
+			report.Renewed = append(report.Renewed, entry)
+			continue
+		}
+		if err := r.wait(ctx); err != nil {
+			return report, err
+		}
+		txid, err := r.cfg.Node.NameUpdate(ctx, n.Name, n.Value, "")
+		r.lastSent = time.Now()
+		if err != nil {
+			entry.Err = err
+			report.Failed = append(report.Failed, entry)
+			continue
+		}
+		entry.Txid = string(txid)
+		report.Renewed = append(report.Renewed, entry)
+	}
+	return report, nil
+}
+
+// skipReason returns why n, which is due, must not be renewed.
+func (r *Renewer) skipReason(n nmcjson.NameListResult, isPending map[string]bool) (SkipReason, bool) {
+	switch {
+	case n.Expired:
+		return SkipExpired, true
+	case n.Transferred:
+		return SkipTransferred, true
+	case n.NameError != "" || n.ValueError != "":
+		return SkipEncoding, true
+	case isPending[n.Name]:
+		return SkipPending, true
+	}
+	for _, re := range r.cfg.Exclude {
+		if re.MatchString(n.Name) {
+			return SkipExcluded, true
+		}
+	}
+	return "", false
+}
+
+// wait sleeps until Spacing has passed since the last name_update.  The
+// caller must hold mtx.
+func (r *Renewer) wait(ctx context.Context) error {
+	delay := time.Until(r.lastSent.Add(r.cfg.Spacing))
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setLast records report as the last one.
+func (r *Renewer) setLast(report *Report) {
+	r.reportMtx.Lock()
+	r.last = report
+	r.reportMtx.Unlock()
+}
+
+// LastReport returns the report of the last poll, or nil if no poll listed
+// the names yet.
+func (r *Renewer) LastReport() *Report {
+	r.reportMtx.Lock()
+	defer r.reportMtx.Unlock()
+	return r.last
+}
+
+// Run polls every PollInterval until ctx is done.  Errors are passed to
+// Config.OnError and retried on the next poll.
+func (r *Renewer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		report, err := r.Poll(ctx)
+		if report != nil && r.cfg.Notify != nil {
+			r.cfg.Notify(report)
+		}
+		if err != nil && ctx.Err() == nil && r.cfg.OnError != nil {
+			r.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
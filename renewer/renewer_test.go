@@ -0,0 +1,195 @@
+package renewer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/nmctest"
+)
+
+// testNode forwards to a Client and lets a test change the replies about the
+// wallet's names.
+type testNode struct {
+	*nmcjson.Client
+
+	// listErr, when not nil, replaces the reply to name_list.
+	listErr error
+
+	// pending are the names reported by name_pending.
+	pending []string
+}
+
+func (n *testNode) NameList(ctx context.Context, name string) ([]nmcjson.NameListResult, error) {
+	if n.listErr != nil {
+		return nil, n.listErr
+	}
+	return n.Client.NameList(ctx, name)
+}
+
+func (n *testNode) NamePending(ctx context.Context, name string) ([]nmcjson.NamePendingResult, error) {
+	results := make([]nmcjson.NamePendingResult, 0, len(n.pending))
+	for _, p := range n.pending {
+		results = append(results, nmcjson.NamePendingResult{NameValue: nmcjson.NameValue{Name: p}, Op: nmcjson.OpNameUpdate, IsMine: true})
+	}
+	return results, nil
+}
+
+// testNames are registered by newTestNode in order, so the first one expires
+// first.
+var testNames = []string{"d/first", "d/second", "d/third"}
+
+// newTestNode returns a node on a fresh nmctest server whose wallet holds
+// testNames.
+func newTestNode(t *testing.T) (*testNode, *nmctest.Server) {
+	t.Helper()
+	ctx := context.Background()
+	s := nmctest.NewServer(nil)
+	t.Cleanup(s.Close)
+	client, err := nmcjson.NewClient(s.ConnConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range testNames {
+		nn, err := client.NameNew(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Generate(12)
+		if _, err := client.NameFirstUpdate(ctx, name, nn.Rand, nn.Txid, "value of "+name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Generate(1)
+	return &testNode{Client: client}, s
+}
+
+// names returns the names of entries, and their reasons when skipped.
+func names(entries []Entry) []string {
+	var result []string
+	for _, e := range entries {
+		if e.Reason != "" {
+			result = append(result, e.Name+": "+string(e.Reason))
+			continue
+		}
+		result = append(result, e.Name)
+	}
+	return result
+}
+
+func TestPoll(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		pending []string
+		renewed []string
+		skipped []string
+	}{{
+		name: "none due",
+	}, {
+		name:    "all due",
+		cfg:     Config{Threshold: 100000},
+		renewed: testNames,
+	}, {
+		name:    "excluded",
+		cfg:     Config{Threshold: 100000, Exclude: []*regexp.Regexp{regexp.MustCompile(`^d/sec`)}},
+		renewed: []string{"d/first", "d/third"},
+		skipped: []string{"d/second: excluded"},
+	}, {
+		name:    "pending",
+		cfg:     Config{Threshold: 100000},
+		pending: []string{"d/first"},
+		renewed: []string{"d/second", "d/third"},
+		skipped: []string{"d/first: operation pending"},
+	}, {
+		name:    "rate limited",
+		cfg:     Config{Threshold: 100000, MaxPerPoll: 2},
+		renewed: []string{"d/first", "d/second"},
+		skipped: []string{"d/third: rate limited"},
+	}, {
+		name:    "dry run",
+		cfg:     Config{Threshold: 100000, DryRun: true},
+		renewed: testNames,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			node, s := newTestNode(t)
+			node.pending = test.pending
+			cfg := test.cfg
+			cfg.Node = node
+			r, err := New(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := r.Poll(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(report.Renewed); !reflect.DeepEqual(got, test.renewed) {
+				t.Fatalf("renewed: got %q, want %q", got, test.renewed)
+			}
+			if got := names(report.Skipped); !reflect.DeepEqual(got, test.skipped) {
+				t.Fatalf("skipped: got %q, want %q", got, test.skipped)
+			}
+			if len(report.Failed) != 0 {
+				t.Fatalf("failed: got %+v, want none", report.Failed)
+			}
+			if r.LastReport() != report {
+				t.Fatal("LastReport is not the report of the poll")
+			}
+
+			for _, e := range report.Renewed {
+				show, err := node.NameShow(ctx, e.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if renewed := show.Height == s.Height(); renewed == test.cfg.DryRun {
+					t.Fatalf("%s: updated at %d with tip %d in dry run %v", e.Name, show.Height, s.Height(), test.cfg.DryRun)
+				}
+				if show.Value != "value of "+e.Name {
+					t.Fatalf("%s: value changed to %q", e.Name, show.Value)
+				}
+				if !test.cfg.DryRun && show.Txid != e.Txid {
+					t.Fatalf("%s: txid got %s, want %s", e.Name, e.Txid, show.Txid)
+				}
+			}
+		})
+	}
+}
+
+func TestRunOnError(t *testing.T) {
+	errNode := errors.New("node unreachable")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	node, _ := newTestNode(t)
+	node.listErr = errNode
+
+	var reports int
+	errs := make(chan error, 1)
+	r, err := New(&Config{
+		Node:         node,
+		PollInterval: time.Hour,
+		Notify:       func(*Report) { reports++ },
+		OnError: func(err error) {
+			errs <- err
+			cancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(ctx); err != context.Canceled {
+		t.Fatalf("Run: got %v, want %v", err, context.Canceled)
+	}
+	if err := <-errs; err != errNode {
+		t.Fatalf("OnError: got %v, want %v", err, errNode)
+	}
+	if reports != 0 {
+		t.Fatalf("Notify called %d times without a report", reports)
+	}
+}
@@ -0,0 +1,148 @@
+package nmcjson
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultScanPageSize is the number of names requested per name_scan by
+// ScanAll when ScanOptions.PageSize is zero.
+const DefaultScanPageSize = 500
+
+// ScanOptions configures ScanAll.
+type ScanOptions struct {
+	// Prefix restricts the scan to names starting with Prefix, such as a
+	// namespace like "d/".  The scan starts at Prefix and stops at the
+	// first name without it, so unlike NameScanOptions.Prefix it works
+	// with every namecoind and does not walk the rest of the database.
+	Prefix string
+
+	// After resumes a scan after the name returned by the Cursor of an
+	// earlier NameScanner.  Only names greater than After are returned.
+	After string
+
+	// PageSize is the number of names requested per name_scan.  Zero
+	// means DefaultScanPageSize, and sizes below 2 are raised to 2 since
+	// every page after the first repeats the last name of the previous
+	// one.
+	PageSize int
+
+	// Options is passed to every name_scan, for instance to select the
+	// name encoding.  Names, Prefix and After use the same encoding.
+	Options *NameScanOptions
+}
+
+// NameScanner walks the name database in name order by paging through
+// name_scan.  It is created by Client.ScanAll and used like bufio.Scanner:
+//
+//	scanner := client.ScanAll(ctx, &nmcjson.ScanOptions{Prefix: "d/"})
+//	for scanner.Next() {
+//		name := scanner.Result()
+//		...
+//	}
+//	if err := scanner.Err(); err != nil {
+//		...
+//	}
+type NameScanner struct {
+	ctx      context.Context
+	client   *Client
+	opts     ScanOptions
+	page     []NameScanResult
+	result   NameScanResult
+	cursor   string
+	lastPage bool
+	err      error
+}
+
+// ScanAll returns a NameScanner over every name, or the names selected by
+// opts, which may be nil.  The boundary name repeated by consecutive pages is
+// only returned once.  The scan stops at the end of the database, on the
+// first error, or when ctx is done.
+func (c *Client) ScanAll(ctx context.Context, opts *ScanOptions) *NameScanner {
+	s := &NameScanner{ctx: ctx, client: c}
+	if opts != nil {
+		s.opts = *opts
+	}
+	switch {
+	case s.opts.PageSize == 0:
+		s.opts.PageSize = DefaultScanPageSize
+	case s.opts.PageSize < 2:
+		s.opts.PageSize = 2
+	}
+	s.cursor = s.opts.After
+	return s
+}
+
+// Next advances to the next name, which is then available through Result.
+// It returns false when the scan stops; Err reports why.
+func (s *NameScanner) Next() bool {
+	for s.err == nil {
+		if err := s.ctx.Err(); err != nil {
+			s.err = err
+			return false
+		}
+		if len(s.page) == 0 {
+			if s.lastPage {
+				return false
+			}
+			s.fetch()
+			continue
+		}
+		r := s.page[0]
+		s.page = s.page[1:]
+
+		// Pages start at the cursor, which was already returned.
+		// Names are never empty, so an empty cursor skips nothing.
+		if r.Name <= s.cursor {
+			continue
+		}
+		if s.opts.Prefix != "" && !strings.HasPrefix(r.Name, s.opts.Prefix) {
+			if r.Name > s.opts.Prefix {
+				// Names are sorted, so no later name has the
+				// prefix.
+				s.page = nil
+				s.lastPage = true
+			}
+			continue
+		}
+		s.result = r
+		s.cursor = r.Name
+		return true
+	}
+	return false
+}
+
+// fetch requests the page of names starting at the cursor, or at the prefix
+// if it comes later.
+func (s *NameScanner) fetch() {
+	start := s.cursor
+	if s.opts.Prefix > start {
+		start = s.opts.Prefix
+	}
+	count := s.opts.PageSize
+	cmd := NewNameScanCmd(&start, &count, s.opts.Options)
+	res, err := s.client.sendCmd(s.ctx, cmd, NameScanReplyParse)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.page = res.([]NameScanResult)
+	s.lastPage = len(s.page) < count
+}
+
+// Result returns the name found by the last call to Next.
+func (s *NameScanner) Result() NameScanResult {
+	return s.result
+}
+
+// Cursor returns a checkpoint of the scan: the name of the last result, or
+// ScanOptions.After if there was none.  Passing it as ScanOptions.After
+// resumes the scan after that name.
+func (s *NameScanner) Cursor() string {
+	return s.cursor
+}
+
+// Err returns the error which stopped the scan, or nil if it reached the end.
+func (s *NameScanner) Err() error {
+	return s.err
+}
@@ -0,0 +1,143 @@
+package nmcjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/btcsuite/btcd/btcjson"
+)
+
+// StreamNameScanReply decodes a name_scan reply from r and calls fn with each
+// result in turn.  r holds either a complete JSON-RPC response or only its
+// result array.  Only one result is held in memory at a time, so replies of
+// any size use bounded memory.  Decoding stops with the error returned by fn,
+// if any.
+func StreamNameScanReply(r io.Reader, fn func(NameScanResult) error) error {
+	return streamReply(r, func(dec *json.Decoder) error {
+		var res NameScanResult
+		if err := dec.Decode(&res); err != nil {
+			return err
+		}
+		return fn(res)
+	})
+}
+
+// StreamNameFilterReply decodes a name_filter reply from r and calls fn with
+// each result in turn, like StreamNameScanReply.
+func StreamNameFilterReply(r io.Reader, fn func(NameFilterResult) error) error {
+	return streamReply(r, func(dec *json.Decoder) error {
+		var res NameFilterResult
+		if err := dec.Decode(&res); err != nil {
+			return err
+		}
+		return fn(res)
+	})
+}
+
+// streamReply reads a reply whose result is an array from r, and calls each
+// to decode every element of the array from dec.
+func streamReply(r io.Reader, each func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('['):
+		return streamArray(dec, each)
+	case json.Delim('{'):
+	default:
+		return fmt.Errorf("unexpected %v at the start of the reply", tok)
+	}
+
+	var rpcErr *btcjson.RPCError
+	var sawResult bool
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "result":
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				// The result of an error reply is null.
+				continue
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("unexpected %v at the start of the result", tok)
+			}
+			if err := streamArray(dec, each); err != nil {
+				return err
+			}
+			sawResult = true
+		case "error":
+			if err := dec.Decode(&rpcErr); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	if rpcErr != nil {
+		return NewRPCError(rpcErr.Code, rpcErr.Message)
+	}
+	if !sawResult {
+		return errors.New("reply has no result")
+	}
+	return nil
+}
+
+// streamArray calls each for every element of the array whose opening
+// bracket was just read from dec, and reads the closing bracket.
+func streamArray(dec *json.Decoder, each func(dec *json.Decoder) error) error {
+	for dec.More() {
+		if err := each(dec); err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// streamCmd sends cmd to the server and passes the reply body to stream
+// without reading it into memory first.
+func (c *Client) streamCmd(ctx context.Context, cmd interface{}, stream func(io.Reader) error) error {
+	resp, err := c.post(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	err = stream(resp.Body)
+	var rpcErr *RPCError
+	if err != nil && !errors.As(err, &rpcErr) && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d: %v", resp.StatusCode, err)
+	}
+	return err
+}
+
+// NameScanEach is like NameScan, but streams the reply and calls fn with each
+// result as soon as it is decoded instead of returning them all.
+func (c *Client) NameScanEach(ctx context.Context, start string, max int, fn func(NameScanResult) error) error {
+	return c.streamCmd(ctx, nameScanCmd(start, max, nil), func(r io.Reader) error {
+		return StreamNameScanReply(r, fn)
+	})
+}
+
+// NameFilterEach is like NameFilter, but streams the reply and calls fn with
+// each result as soon as it is decoded instead of returning them all.
+func (c *Client) NameFilterEach(ctx context.Context, regexp string, maxAge, from, nb int, fn func(NameFilterResult) error) error {
+	return c.streamCmd(ctx, c.nameFilterCmd(regexp, maxAge, from, nb), func(r io.Reader) error {
+		return StreamNameFilterReply(r, fn)
+	})
+}
@@ -0,0 +1,78 @@
+package nmcjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchFilterResults is the number of names in the reply of the name_filter
+// benchmarks.
+const benchFilterResults = 20000
+
+// benchFilterReply returns a complete JSON-RPC response to name_filter with
+// n names, as namecoind sends it.
+func benchFilterReply(n int) []byte {
+	results := make([]NameFilterResult, n)
+	for i := range results {
+		results[i] = NameFilterResult{
+			NameValue: NameValue{
+				Name:  fmt.Sprintf("d/name%06d", i),
+				Value: fmt.Sprintf(`{"ip":"192.0.2.%d","map":{"www":{"alias":""}}}`, i%256),
+			},
+			Txid:      fmt.Sprintf("%064x", i),
+			Vout:      i % 3,
+			Address:   "N4wXhz8wLHEK3Y9mBoD9XNnHkkGbd4tDmb",
+			Height:    int64(400000 + i),
+			ExpiresIn: int64(36000 - i%36000),
+		}
+	}
+	result, err := json.Marshal(results)
+	if err != nil {
+		panic(err)
+	}
+	return []byte(`{"result":` + string(result) + `,"error":null,"id":1}`)
+}
+
+func BenchmarkStreamNameFilterReply(b *testing.B) {
+	reply := benchFilterReply(benchFilterResults)
+	b.SetBytes(int64(len(reply)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var count int
+		err := StreamNameFilterReply(bytes.NewReader(reply), func(NameFilterResult) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if count != benchFilterResults {
+			b.Fatalf("streamed %d results, want %d", count, benchFilterResults)
+		}
+	}
+}
+
+func BenchmarkNameFilterReplyParse(b *testing.B) {
+	reply := benchFilterReply(benchFilterResults)
+	b.SetBytes(int64(len(reply)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Unmarshal the envelope as Client does before parsing the
+		// result.
+		var r rawReply
+		if err := json.Unmarshal(reply, &r); err != nil {
+			b.Fatal(err)
+		}
+		res, err := NameFilterReplyParse(r.Result)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if n := len(res.([]NameFilterResult)); n != benchFilterResults {
+			b.Fatalf("parsed %d results, want %d", n, benchFilterResults)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+/*
+Package tracker follows the values of a set of names and reports when they
+change, including when a chain reorganisation undoes an update.
+
+For every tracked name, a Tracker keeps the name_history entries it has
+seen, each with the hash of the block at its Height.  Poll first compares
+those hashes with the blocks now at the same heights.  Entries whose block
+was reorganised away are rolled back, falling back to the previous entry,
+and the current value is then read again with name_show.  An Event is
+emitted for every name whose effective value, owning output or expiry
+differs from the one before the poll, with Reorg set when a rollback was
+involved.
+
+Caches of name values can subscribe through Config.Notify and invalidate
+exactly the names which changed.  Run polls in a loop and passes the errors
+of failed polls to Config.OnError.
+*/
+package tracker
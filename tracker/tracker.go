@@ -0,0 +1,316 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+)
+
+// DefaultPollInterval is the interval between polls of Run when
+// Config.PollInterval is zero.
+const DefaultPollInterval = time.Minute
+
+// Node is the subset of nmcjson.Client used by the tracker.
+type Node interface {
+	GetBlockHash(ctx context.Context, height int64) (string, error)
+	NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error)
+	NameHistory(ctx context.Context, name string) ([]nmcjson.NameHistoryResult, error)
+}
+
+// Enforce that nmcjson.Client satisfies the Node interface.
+var _ Node = (*nmcjson.Client)(nil)
+
+// Config is the configuration of a Tracker.
+type Config struct {
+	// Node is the namecoind connection used to read names and blocks.
+	Node Node
+
+	// PollInterval is how often Run polls.  Zero means
+	// DefaultPollInterval.
+	PollInterval time.Duration
+
+	// Notify, when not nil, is called with every event emitted by Poll.
+	Notify func(Event)
+
+	// OnError, when not nil, is called by Run with every error of a
+	// poll.
+	OnError func(error)
+}
+
+// Record is a value of a name along with the hash of the block, at its
+// Height, in which it was set.
+type Record struct {
+	nmcjson.NameHistoryResult
+	BlockHash string
+}
+
+// Event describes a change of the effective value of a name, of the output
+// holding it or of its expiry.
+type Event struct {
+	Name string
+
+	// Old is the value before the change, or nil if the name did not
+	// exist.
+	Old *Record
+
+	// New is the value after the change, or nil if the name no longer
+	// exists.
+	New *Record
+
+	// Reorg is set when the change undid a value whose block was
+	// reorganised away.
+	Reorg bool
+}
+
+// Tracker follows the values of a set of names across chain
+// reorganisations.
+type Tracker struct {
+	cfg     Config
+	pollMtx sync.Mutex // serialises Poll
+
+	mtx   sync.Mutex
+	names map[string][]Record // oldest first
+}
+
+// New returns a Tracker which tracks no names yet.
+func New(cfg *Config) (*Tracker, error) {
+	if cfg.Node == nil {
+		return nil, errors.New("tracker: node is required")
+	}
+	t := &Tracker{
+		cfg:   *cfg,
+		names: make(map[string][]Record),
+	}
+	if t.cfg.PollInterval == 0 {
+		t.cfg.PollInterval = DefaultPollInterval
+	}
+	return t, nil
+}
+
+// Track starts tracking name, seeding its records with name_history so that
+// reorganisations can be rolled back to earlier values.  When the node does
+// not keep name histories, only the name_show value is recorded.  A name
+// which does not exist yet is tracked too, and reported once it appears.
+func (t *Tracker) Track(ctx context.Context, name string) error {
+	history, err := t.cfg.Node.NameHistory(ctx, name)
+	var rpcErr *nmcjson.RPCError
+	if err != nil && errors.As(err, &rpcErr) && !errors.Is(err, nmcjson.ErrNameNotFound) {
+		var show *nmcjson.NameShowResult
+		show, err = t.cfg.Node.NameShow(ctx, name)
+		history = nil
+		if err == nil {
+			history = []nmcjson.NameHistoryResult{nmcjson.NameHistoryResult(*show)}
+		}
+	}
+	if err != nil && !errors.Is(err, nmcjson.ErrNameNotFound) {
+		return err
+	}
+
+	hashes := newHashCache(t.cfg.Node)
+	records := make([]Record, 0, len(history))
+	for _, h := range history {
+		hash, err := hashes.at(ctx, h.Height)
+		if err != nil {
+			return err
+		}
+		records = append(records, Record{NameHistoryResult: h, BlockHash: hash})
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.names[name] = records
+	return nil
+}
+
+// Untrack stops tracking name.
+func (t *Tracker) Untrack(name string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	delete(t.names, name)
+}
+
+// Current returns the current value of the tracked name.  It returns false
+// if the name is not tracked or does not exist.
+func (t *Tracker) Current(name string) (Record, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	records := t.names[name]
+	if len(records) == 0 {
+		return Record{}, false
+	}
+	return records[len(records)-1], true
+}
+
+// Poll checks every tracked name once, rolling back values set in blocks
+// which were reorganised away and reading the current values, and returns
+// an Event for every name whose effective record changed.  The events are
+// also passed to Config.Notify.  On error, the events of the names checked
+// so far are returned with it.
+func (t *Tracker) Poll(ctx context.Context) ([]Event, error) {
+	t.pollMtx.Lock()
+	defer t.pollMtx.Unlock()
+
+	t.mtx.Lock()
+	names := make([]string, 0, len(t.names))
+	for name := range t.names {
+		names = append(names, name)
+	}
+	t.mtx.Unlock()
+
+	hashes := newHashCache(t.cfg.Node)
+	var events []Event
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return events, err
+		}
+		event, err := t.check(ctx, name, hashes)
+		if err != nil {
+			return events, err
+		}
+		if event != nil {
+			events = append(events, *event)
+			if t.cfg.Notify != nil {
+				t.cfg.Notify(*event)
+			}
+		}
+	}
+	return events, nil
+}
+
+// check rolls back and refreshes the records of name, and returns the event
+// describing the change of its value, if any.
+func (t *Tracker) check(ctx context.Context, name string, hashes *hashCache) (*Event, error) {
+	t.mtx.Lock()
+	tracked, ok := t.names[name]
+	records := append([]Record(nil), tracked...)
+	t.mtx.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	old := last(records)
+
+	var reorg bool
+	for len(records) > 0 {
+		top := records[len(records)-1]
+		hash, err := hashes.at(ctx, top.Height)
+		if err != nil {
+			return nil, err
+		}
+		if hash == top.BlockHash {
+			break
+		}
+		records = records[:len(records)-1]
+		reorg = true
+	}
+
+	show, err := t.cfg.Node.NameShow(ctx, name)
+	switch {
+	case errors.Is(err, nmcjson.ErrNameNotFound):
+		records = nil
+	case err != nil:
+		return nil, err
+	case len(records) > 0 && sameOutput(records[len(records)-1], show):
+		// Only the expiry moved on.
+		records[len(records)-1].NameHistoryResult = nmcjson.NameHistoryResult(*show)
+	default:
+		hash, err := hashes.at(ctx, show.Height)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{
+			NameHistoryResult: nmcjson.NameHistoryResult(*show),
+			BlockHash:         hash,
+		})
+	}
+
+	t.mtx.Lock()
+	if _, ok := t.names[name]; ok {
+		t.names[name] = records
+	}
+	t.mtx.Unlock()
+
+	current := last(records)
+	if !changed(old, current) {
+		return nil, nil
+	}
+	return &Event{Name: name, Old: old, New: current, Reorg: reorg}, nil
+}
+
+// Run polls every PollInterval until ctx is done.  Errors are passed to
+// Config.OnError and retried on the next poll.
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := t.Poll(ctx); err != nil && ctx.Err() == nil && t.cfg.OnError != nil {
+			t.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// last returns a copy of the last of records, or nil if there are none.
+func last(records []Record) *Record {
+	if len(records) == 0 {
+		return nil
+	}
+	r := records[len(records)-1]
+	return &r
+}
+
+// sameOutput reports whether r and show describe the same name output in the
+// same block.
+func sameOutput(r Record, show *nmcjson.NameShowResult) bool {
+	return r.Txid == show.Txid && r.Vout == show.Vout && r.Height == show.Height
+}
+
+// changed reports whether the effective value, the output holding the name or
+// its expiry differs between old and current, either of which may be nil
+// when the name does not exist.  An update to the same value still moves the
+// name, possibly to another owner.
+func changed(old, current *Record) bool {
+	if old == nil || current == nil {
+		return old != current
+	}
+	return old.Value != current.Value || old.Expired != current.Expired ||
+		old.Txid != current.Txid || old.Vout != current.Vout
+}
+
+// hashCache remembers the block hashes read during one poll, so names set
+// in the same block share one getblockhash.
+type hashCache struct {
+	node   Node
+	hashes map[int64]string
+}
+
+// newHashCache returns an empty hashCache reading from node.
+func newHashCache(node Node) *hashCache {
+	return &hashCache{node: node, hashes: make(map[int64]string)}
+}
+
+// at returns the hash of the block at height, or an empty hash if the chain
+// is not that long.  namecoind replies to getblockhash above its tip with
+// RPC_INVALID_PARAMETER.
+func (c *hashCache) at(ctx context.Context, height int64) (string, error) {
+	if hash, ok := c.hashes[height]; ok {
+		return hash, nil
+	}
+	hash, err := c.node.GetBlockHash(ctx, height)
+	var rpcErr *nmcjson.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == btcjson.ErrRPCInvalidParameter {
+		hash, err = "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	c.hashes[height] = hash
+	return hash, nil
+}
@@ -0,0 +1,233 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/nmctest"
+)
+
+// shortNode forwards to a Client, but can make the chain appear shorter
+// than it is, as when namecoind switches to a shorter chain with more work.
+type shortNode struct {
+	*nmcjson.Client
+
+	// tip, when not negative, is the height the chain appears to have.
+	tip int64
+
+	// err, when not nil, replaces the reply to name_show.
+	err error
+}
+
+func (n *shortNode) GetBlockHash(ctx context.Context, height int64) (string, error) {
+	if n.tip >= 0 && height > n.tip {
+		return "", nmcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Block height out of range")
+	}
+	return n.Client.GetBlockHash(ctx, height)
+}
+
+func (n *shortNode) NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	return n.Client.NameShow(ctx, name)
+}
+
+// newTestTracker returns a tracker on a fresh nmctest server on which
+// d/example was registered with value "a".
+func newTestTracker(t *testing.T, cfg *Config) (*Tracker, *shortNode, *nmctest.Server) {
+	t.Helper()
+	ctx := context.Background()
+	s := nmctest.NewServer(nil)
+	t.Cleanup(s.Close)
+	client, err := nmcjson.NewClient(s.ConnConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nn, err := client.NameNew(ctx, "d/example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(12)
+	if _, err := client.NameFirstUpdate(ctx, "d/example", nn.Rand, nn.Txid, "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	s.Generate(5)
+
+	node := &shortNode{Client: client, tip: -1}
+	cfg.Node = node
+	tr, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Track(ctx, "d/example"); err != nil {
+		t.Fatal(err)
+	}
+	return tr, node, s
+}
+
+func TestPoll(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int64  // of the reorganisation after the update, if any
+		value string // after the poll, empty if the name is gone
+	}{
+		{"update", 0, "b"},
+		{"update reorganised away", 1, "a"},
+		{"update and registration reorganised away", 10, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr, node, s := newTestTracker(t, &Config{})
+			if _, err := node.NameUpdate(ctx, "d/example", "b", ""); err != nil {
+				t.Fatal(err)
+			}
+			if test.depth > 0 {
+				s.Reorg(test.depth)
+			}
+			events, err := tr.Poll(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.value == "a" {
+				if len(events) != 0 {
+					t.Fatalf("events: got %+v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Old == nil || events[0].Old.Value != "a" {
+				t.Fatalf("events: got %+v, want one from value a", events)
+			}
+			current, ok := tr.Current("d/example")
+			switch {
+			case test.value == "" && ok:
+				t.Fatalf("Current: got %+v, want none", current)
+			case test.value != "" && (!ok || current.Value != test.value):
+				t.Fatalf("Current: got %+v, want value %q", current, test.value)
+			}
+		})
+	}
+}
+
+func TestPollChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, node *shortNode, s *nmctest.Server)
+	}{
+		{"same value", func(t *testing.T, node *shortNode, s *nmctest.Server) {
+			if _, err := node.NameUpdate(context.Background(), "d/example", "a", ""); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"transfer", func(t *testing.T, node *shortNode, s *nmctest.Server) {
+			if _, err := node.NameUpdate(context.Background(), "d/example", "a", "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"expiry", func(t *testing.T, node *shortNode, s *nmctest.Server) {
+			s.Generate(s.Params().NameExpirationDepth(s.Height()))
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr, node, s := newTestTracker(t, &Config{})
+			test.change(t, node, s)
+			events, err := tr.Poll(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || events[0].Old == nil || events[0].New == nil {
+				t.Fatalf("events: got %+v, want one", events)
+			}
+			old, current := events[0].Old, events[0].New
+			if old.Txid == current.Txid && old.Expired == current.Expired {
+				t.Fatalf("event: got %+v, want a new txid or expiry", events[0])
+			}
+			if current.Value != "a" {
+				t.Fatalf("event: got value %q, want %q", current.Value, "a")
+			}
+		})
+	}
+}
+
+func TestPollReorg(t *testing.T) {
+	ctx := context.Background()
+	tr, node, s := newTestTracker(t, &Config{})
+	s.Generate(1)
+	if _, err := node.NameUpdate(ctx, "d/example", "b", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Reorg(1)
+	events, err := tr.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || !events[0].Reorg || events[0].Old.Value != "b" || events[0].New.Value != "a" {
+		t.Fatalf("events: got %+v, want reorg from b to a", events)
+	}
+}
+
+func TestPollChainShrinks(t *testing.T) {
+	ctx := context.Background()
+	tr, node, s := newTestTracker(t, &Config{})
+	s.Generate(1)
+	if _, err := node.NameUpdate(ctx, "d/example", "b", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The block of the update is no longer served while name_show still
+	// returns the update.
+	node.tip = s.Height() - 1
+	events, err := tr.Poll(ctx)
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("events: got %+v, want none", events)
+	}
+	if current, ok := tr.Current("d/example"); !ok || current.Value != "b" {
+		t.Fatalf("Current: got %+v, want value b", current)
+	}
+}
+
+func TestRunOnError(t *testing.T) {
+	errNode := errors.New("node unreachable")
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, node, _ := newTestTracker(t, &Config{})
+	tr, err := New(&Config{
+		Node:         node,
+		PollInterval: time.Hour,
+		OnError: func(err error) {
+			errs <- err
+			cancel()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Track(ctx, "d/example"); err != nil {
+		t.Fatal(err)
+	}
+	node.err = errNode
+	if err := tr.Run(ctx); err != context.Canceled {
+		t.Fatalf("Run: got %v, want %v", err, context.Canceled)
+	}
+	if err := <-errs; err != errNode {
+		t.Fatalf("OnError: got %v, want %v", err, errNode)
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Package trade builds and checks atomic name-for-coins swaps.
+
+A swap is a single transaction which spends the name output of the seller
+and coins of the buyer, and creates a name_update sending the name to the
+buyer along with a payment to the seller.  Since each party only signs its
+own inputs with SIGHASH_ALL, neither signature is valid unless the whole
+transaction is mined, so neither party can cheat the other.
+
+# Flow
+
+Both parties agree on Terms, and the seller shares the name_show result of
+the name as the listing.
+
+ 1. The buyer calls Confirm to check the listing against namecoind, then
+    Propose with its own inputs and change output.  It signs its inputs,
+    for example with signrawtransactionwithwallet, giving the buyer half.
+ 2. The seller calls Confirm to check it still owns the name at the listed
+    outpoint, and Verify on the buyer half.  It signs the name input of the
+    unsigned transaction, giving the seller half.
+ 3. Either party calls Combine on the two halves, checks the result with
+    Verify, and broadcasts it.
+
+The name output is built with the namerawtransaction rules of
+namescript.AttachNameOp, so the unsigned transaction can be cross-checked
+against the namerawtransaction RPC of the node.
+*/
+package trade
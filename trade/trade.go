@@ -0,0 +1,328 @@
+package trade
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+var (
+	// ErrListingMismatch indicates a listing which does not match the
+	// terms or the current state of the name.
+	ErrListingMismatch = errors.New("listing does not match")
+
+	// ErrTermsViolated indicates a swap transaction which does not follow
+	// the terms.
+	ErrTermsViolated = errors.New("transaction violates the terms")
+
+	// ErrHalvesMismatch indicates two halves which are not signatures of
+	// the same transaction.
+	ErrHalvesMismatch = errors.New("halves are not the same transaction")
+)
+
+// Terms are the agreed terms of a swap.
+type Terms struct {
+	// Name is the raw name being sold.
+	Name string
+
+	// Value is the value set by the name_update which transfers the name.
+	Value string
+
+	// Price is paid to SellerAddress.
+	Price btcutil.Amount
+
+	// SellerAddress receives the price.
+	SellerAddress string
+
+	// BuyerAddress receives the name.
+	BuyerAddress string
+
+	// Params are the params of the network of the swap, which set the
+	// network of the addresses and the amount locked in the name output.
+	// Nil means nmcjson.MainNetParams.
+	Params *nmcjson.Params
+}
+
+// params returns the params of t.
+func (t *Terms) params() *nmcjson.Params {
+	if t.Params == nil {
+		return &nmcjson.MainNetParams
+	}
+	return t.Params
+}
+
+// NameOp returns the namerawtransaction operation which transfers the name.
+func (t *Terms) NameOp() nmcjson.NameOp {
+	return nmcjson.NameOp{
+		Op:    nmcjson.OpNameUpdate,
+		Name:  t.Name,
+		Value: t.Value,
+	}
+}
+
+// Validate checks the name, value, price and addresses of t.
+func (t *Terms) Validate() error {
+	if err := t.NameOp().Validate(); err != nil {
+		return err
+	}
+	if t.Price <= 0 {
+		return fmt.Errorf("invalid price %s", nmc(t.Price))
+	}
+	if _, err := address.Decode(t.SellerAddress, t.params()); err != nil {
+		return fmt.Errorf("seller: %v", err)
+	}
+	if _, err := address.Decode(t.BuyerAddress, t.params()); err != nil {
+		return fmt.Errorf("buyer: %v", err)
+	}
+	return nil
+}
+
+// CheckListing checks that listing, a name_show result, describes the name
+// of t and that the name has not expired.
+func CheckListing(t *Terms, listing *nmcjson.NameShowResult) error {
+	name, err := listing.NameBytes()
+	if err != nil {
+		return err
+	}
+	if string(name) != t.Name {
+		return fmt.Errorf("%w: listing is for %q, not %q", ErrListingMismatch, name, t.Name)
+	}
+	if listing.Expired {
+		return fmt.Errorf("%w: %q has expired", ErrListingMismatch, t.Name)
+	}
+	return nil
+}
+
+// NameShower is the subset of nmcjson.Client used to confirm listings.
+type NameShower interface {
+	NameShow(ctx context.Context, name string) (*nmcjson.NameShowResult, error)
+}
+
+// Enforce that nmcjson.Client satisfies the NameShower interface.
+var _ NameShower = (*nmcjson.Client)(nil)
+
+// Confirm checks listing against t and against the current state of the name
+// reported by node.  The name must still be at the listed outpoint
+// (Txid/Vout) and owned by the listed address, so the swap spends the
+// current name output.
+func Confirm(ctx context.Context, node NameShower, t *Terms, listing *nmcjson.NameShowResult) error {
+	if err := CheckListing(t, listing); err != nil {
+		return err
+	}
+	current, err := node.NameShow(ctx, t.Name)
+	if err != nil {
+		return err
+	}
+	if current.Expired {
+		return fmt.Errorf("%w: %q has expired", ErrListingMismatch, t.Name)
+	}
+	if current.Txid != listing.Txid || current.Vout != listing.Vout {
+		return fmt.Errorf("%w: %q moved from %s:%d to %s:%d", ErrListingMismatch,
+			t.Name, listing.Txid, listing.Vout, current.Txid, current.Vout)
+	}
+	if current.Address != listing.Address {
+		return fmt.Errorf("%w: %q is owned by %s, not %s", ErrListingMismatch,
+			t.Name, current.Address, listing.Address)
+	}
+	return nil
+}
+
+// addressScript returns the output script paying to addr, an address of
+// the network of params.
+func addressScript(addr string, params *nmcjson.Params) ([]byte, error) {
+	a, err := address.Decode(addr, params)
+	if err != nil {
+		return nil, err
+	}
+	return a.Script(), nil
+}
+
+// listingOutPoint returns the outpoint of the name output of listing.
+func listingOutPoint(listing *nmcjson.NameShowResult) (*wire.OutPoint, error) {
+	hash, err := chainhash.NewHashFromStr(listing.Txid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid txid: %v", ErrListingMismatch, err)
+	}
+	return wire.NewOutPoint(hash, uint32(listing.Vout)), nil
+}
+
+// Propose returns the unsigned swap transaction for t.  Its first input spends
+// the name output of listing and is followed by inputs, which must fund the
+// price and the fee.  Its outputs are the name_update to the buyer, the
+// payment to the seller and, when not nil, change.
+func Propose(t *Terms, listing *nmcjson.NameShowResult, inputs []*wire.TxIn, change *wire.TxOut) (*wire.MsgTx, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if err := CheckListing(t, listing); err != nil {
+		return nil, err
+	}
+	nameOutPoint, err := listingOutPoint(listing)
+	if err != nil {
+		return nil, err
+	}
+	buyerScript, err := addressScript(t.BuyerAddress, t.params())
+	if err != nil {
+		return nil, err
+	}
+	sellerScript, err := addressScript(t.SellerAddress, t.params())
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(namescript.NamecoinTxVersion)
+	tx.AddTxIn(wire.NewTxIn(nameOutPoint, nil, nil))
+	for _, in := range inputs {
+		tx.AddTxIn(wire.NewTxIn(&in.PreviousOutPoint, nil, nil))
+		tx.TxIn[len(tx.TxIn)-1].Sequence = in.Sequence
+	}
+	// The name input carries the locked amount over to the name output.
+	nameAmount := t.params().NameLockedAmount
+	tx.AddTxOut(wire.NewTxOut(int64(nameAmount), buyerScript))
+	if _, err := namescript.AttachNameOp(tx, 0, t.NameOp()); err != nil {
+		return nil, err
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(t.Price), sellerScript))
+	if change != nil {
+		tx.AddTxOut(wire.NewTxOut(change.Value, change.PkScript))
+	}
+	return tx, nil
+}
+
+// Verify checks that tx, a half or a complete swap, follows t: it spends the
+// name output of listing, has exactly one name output, which is a
+// name_update of the agreed name and value to the buyer, and pays at least
+// the price to the seller.  ErrTermsViolated is returned, wrapped, when it
+// does not.
+func Verify(t *Terms, listing *nmcjson.NameShowResult, tx *wire.MsgTx) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if err := CheckListing(t, listing); err != nil {
+		return err
+	}
+	nameOutPoint, err := listingOutPoint(listing)
+	if err != nil {
+		return err
+	}
+	buyerScript, err := addressScript(t.BuyerAddress, t.params())
+	if err != nil {
+		return err
+	}
+	sellerScript, err := addressScript(t.SellerAddress, t.params())
+	if err != nil {
+		return err
+	}
+
+	if tx.Version != namescript.NamecoinTxVersion {
+		return fmt.Errorf("%w: version %#x is not a name transaction", ErrTermsViolated, tx.Version)
+	}
+	spends := 0
+	for _, in := range tx.TxIn {
+		if in.PreviousOutPoint == *nameOutPoint {
+			spends++
+		}
+	}
+	if spends != 1 {
+		return fmt.Errorf("%w: the name output %v is spent %d times", ErrTermsViolated, nameOutPoint, spends)
+	}
+
+	nameAmount := t.params().NameLockedAmount
+	var nameOuts int
+	var paid btcutil.Amount
+	for i, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, sellerScript) {
+			paid += btcutil.Amount(out.Value)
+		}
+		s, err := namescript.Decode(out.PkScript)
+		if err != nil {
+			continue
+		}
+		nameOuts++
+		switch {
+		case s.Op != namescript.OpNameUpdate:
+			return fmt.Errorf("%w: output %d is a %v", ErrTermsViolated, i, s.Op)
+		case string(s.Name) != t.Name:
+			return fmt.Errorf("%w: output %d updates %q", ErrTermsViolated, i, s.Name)
+		case string(s.Value) != t.Value:
+			return fmt.Errorf("%w: output %d sets the value %q", ErrTermsViolated, i, s.Value)
+		case !bytes.Equal(s.AddressScript, buyerScript):
+			return fmt.Errorf("%w: output %d does not send the name to %s",
+				ErrTermsViolated, i, t.BuyerAddress)
+		case btcutil.Amount(out.Value) < nameAmount:
+			return fmt.Errorf("%w: output %d locks %s, less than %s",
+				ErrTermsViolated, i, nmc(btcutil.Amount(out.Value)), nmc(nameAmount))
+		}
+	}
+	if nameOuts != 1 {
+		return fmt.Errorf("%w: %d name outputs", ErrTermsViolated, nameOuts)
+	}
+	if paid < t.Price {
+		return fmt.Errorf("%w: pays %s to %s, the price is %s",
+			ErrTermsViolated, nmc(paid), t.SellerAddress, nmc(t.Price))
+	}
+	return nil
+}
+
+// Unsigned returns a copy of tx without signatures, which is the same for
+// both halves of a swap.
+func Unsigned(tx *wire.MsgTx) *wire.MsgTx {
+	unsigned := tx.Copy()
+	for _, in := range unsigned.TxIn {
+		in.SignatureScript = nil
+		in.Witness = nil
+	}
+	return unsigned
+}
+
+// Combine merges the signatures of the halves a and b, which must be the same
+// transaction signed by different parties.  Inputs signed in both halves
+// must carry the same signature.
+func Combine(a, b *wire.MsgTx) (*wire.MsgTx, error) {
+	if Unsigned(a).TxHash() != Unsigned(b).TxHash() {
+		return nil, ErrHalvesMismatch
+	}
+	combined := a.Copy()
+	for i, in := range combined.TxIn {
+		other := b.TxIn[i]
+		if len(other.SignatureScript) != 0 {
+			if len(in.SignatureScript) != 0 && !bytes.Equal(in.SignatureScript, other.SignatureScript) {
+				return nil, fmt.Errorf("%w: input %d is signed differently", ErrHalvesMismatch, i)
+			}
+			in.SignatureScript = other.SignatureScript
+		}
+		if len(other.Witness) != 0 {
+			if len(in.Witness) != 0 && !witnessEqual(in.Witness, other.Witness) {
+				return nil, fmt.Errorf("%w: input %d is signed differently", ErrHalvesMismatch, i)
+			}
+			in.Witness = other.Witness
+		}
+	}
+	return combined, nil
+}
+
+// nmc formats a in NMC, since btcutil.Amount prints BTC.
+func nmc(a btcutil.Amount) string {
+	return fmt.Sprintf("%v NMC", a.ToBTC())
+}
+
+// witnessEqual reports whether a and b hold the same items.
+func witnessEqual(a, b wire.TxWitness) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
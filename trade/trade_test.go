@@ -0,0 +1,279 @@
+package trade
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/kefkius/nmcjson"
+	"github.com/kefkius/nmcjson/address"
+	"github.com/kefkius/nmcjson/namescript"
+)
+
+// testAddress returns the mainnet P2PKH address of a hash filled with b.
+func testAddress(t *testing.T, b byte) address.Address {
+	t.Helper()
+	a, err := address.NewPubKeyHash(bytes.Repeat([]byte{b}, 20), &nmcjson.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// outPoint returns an outpoint of a txid filled with b.
+func outPoint(t *testing.T, b string, index uint32) *wire.OutPoint {
+	t.Helper()
+	hash, err := chainhash.NewHashFromStr(strings.Repeat(b, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wire.NewOutPoint(hash, index)
+}
+
+// fixture is a swap of d/example for 1 NMC and its proposed transaction,
+// funded by one input of the buyer with change to a third address.
+type fixture struct {
+	terms   *Terms
+	listing *nmcjson.NameShowResult
+	tx      *wire.MsgTx
+
+	buyer, seller, other address.Address
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{
+		buyer:  testAddress(t, 1),
+		seller: testAddress(t, 2),
+		other:  testAddress(t, 3),
+	}
+	f.terms = &Terms{
+		Name:          "d/example",
+		Value:         `{"ip":"192.0.2.1"}`,
+		Price:         btcutil.SatoshiPerBitcoin,
+		SellerAddress: f.seller.String(),
+		BuyerAddress:  f.buyer.String(),
+	}
+	f.listing = &nmcjson.NameShowResult{
+		NameValue: nmcjson.NameValue{Name: "d/example", Value: "{}"},
+		Txid:      strings.Repeat("1", 64),
+		Vout:      1,
+		Address:   f.seller.String(),
+	}
+	funding := wire.NewTxIn(outPoint(t, "2", 0), nil, nil)
+	change := wire.NewTxOut(5000, f.other.Script())
+	tx, err := Propose(f.terms, f.listing, []*wire.TxIn{funding}, change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.tx = tx
+	return f
+}
+
+// nameScript returns the name_update script of name and value to addr.
+func nameScript(t *testing.T, name, value string, addr address.Address) []byte {
+	t.Helper()
+	script, err := namescript.NewNameUpdate([]byte(name), []byte(value), addr.Script()).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestPropose(t *testing.T) {
+	f := newFixture(t)
+	if f.tx.TxIn[0].PreviousOutPoint != *outPoint(t, "1", 1) {
+		t.Fatalf("input 0 spends %v, want the listed name output", f.tx.TxIn[0].PreviousOutPoint)
+	}
+	if len(f.tx.TxIn) != 2 || len(f.tx.TxOut) != 3 {
+		t.Fatalf("got %d inputs and %d outputs, want 2 and 3", len(f.tx.TxIn), len(f.tx.TxOut))
+	}
+	if !bytes.Equal(f.tx.TxOut[0].PkScript, nameScript(t, f.terms.Name, f.terms.Value, f.buyer)) {
+		t.Fatal("output 0 is not the name_update to the buyer")
+	}
+	if f.tx.TxOut[1].Value != int64(f.terms.Price) || !bytes.Equal(f.tx.TxOut[1].PkScript, f.seller.Script()) {
+		t.Fatal("output 1 does not pay the price to the seller")
+	}
+	if err := Verify(f.terms, f.listing, f.tx); err != nil {
+		t.Fatalf("proposal does not verify: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *fixture)
+		err    error
+	}{
+		{"zero price", func(f *fixture) { f.terms.Price = 0 }, nil},
+		{"testnet buyer", func(f *fixture) { f.terms.BuyerAddress = "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth" }, nil},
+		{"listing of another name", func(f *fixture) { f.listing.Name = "d/other" }, ErrListingMismatch},
+		{"expired listing", func(f *fixture) { f.listing.Expired = true }, ErrListingMismatch},
+		{"malformed listing txid", func(f *fixture) { f.listing.Txid = "xyz" }, ErrListingMismatch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			test.modify(f)
+			_, err := Propose(f.terms, f.listing, nil, nil)
+			if err == nil || test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	locked := int64(nmcjson.MainNetParams.NameLockedAmount)
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, f *fixture)
+		ok     bool
+	}{
+		{"proposal", func(t *testing.T, f *fixture) {}, true},
+		{"price paid one satoshi short", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[1].Value--
+		}, false},
+		{"price paid over two outputs", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[1].Value--
+			f.tx.AddTxOut(wire.NewTxOut(1, f.seller.Script()))
+		}, true},
+		{"price overpaid", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[1].Value++
+		}, true},
+		{"wrong name", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].PkScript = nameScript(t, "d/other", f.terms.Value, f.buyer)
+		}, false},
+		{"wrong value", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].PkScript = nameScript(t, f.terms.Name, "{}", f.buyer)
+		}, false},
+		{"name paid to another script", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].PkScript = nameScript(t, f.terms.Name, f.terms.Value, f.other)
+		}, false},
+		{"name paid to the seller", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].PkScript = nameScript(t, f.terms.Name, f.terms.Value, f.seller)
+		}, false},
+		{"name input not spent", func(t *testing.T, f *fixture) {
+			f.tx.TxIn = f.tx.TxIn[1:]
+		}, false},
+		{"name input spent twice", func(t *testing.T, f *fixture) {
+			f.tx.AddTxIn(wire.NewTxIn(&f.tx.TxIn[0].PreviousOutPoint, nil, nil))
+		}, false},
+		{"other output of the name transaction spent", func(t *testing.T, f *fixture) {
+			f.tx.TxIn[0].PreviousOutPoint.Index = 0
+		}, false},
+		{"second name_update output", func(t *testing.T, f *fixture) {
+			f.tx.AddTxOut(wire.NewTxOut(locked, f.tx.TxOut[0].PkScript))
+		}, false},
+		{"name_firstupdate output", func(t *testing.T, f *fixture) {
+			script, err := namescript.NewNameFirstUpdate([]byte(f.terms.Name), make([]byte, nmcjson.RandLength),
+				[]byte(f.terms.Value), f.buyer.Script()).Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.tx.TxOut[0].PkScript = script
+		}, false},
+		{"no name output", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].PkScript = f.buyer.Script()
+		}, false},
+		{"name output below the locked amount", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].Value = locked - 1
+		}, false},
+		{"name output above the locked amount", func(t *testing.T, f *fixture) {
+			f.tx.TxOut[0].Value = locked + 1
+		}, true},
+		{"not a name transaction", func(t *testing.T, f *fixture) {
+			f.tx.Version = 1
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			test.modify(t, f)
+			err := Verify(f.terms, f.listing, f.tx)
+			switch {
+			case test.ok && err != nil:
+				t.Fatalf("got %v, want no error", err)
+			case !test.ok && !errors.Is(err, ErrTermsViolated):
+				t.Fatalf("got %v, want %v", err, ErrTermsViolated)
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	sigA, sigB := []byte{0xaa}, []byte{0xbb}
+	witness := wire.TxWitness{[]byte{0xcc}, []byte{0xdd}}
+	tests := []struct {
+		name string
+		// modify signs the seller half a and the buyer half b.
+		modify func(t *testing.T, a, b *wire.MsgTx)
+		ok     bool
+	}{
+		{"halves", func(t *testing.T, a, b *wire.MsgTx) {
+			a.TxIn[0].SignatureScript = sigA
+			b.TxIn[1].SignatureScript = sigB
+		}, true},
+		{"witness halves", func(t *testing.T, a, b *wire.MsgTx) {
+			a.TxIn[0].SignatureScript = sigA
+			b.TxIn[1].Witness = witness
+		}, true},
+		{"same signature in both", func(t *testing.T, a, b *wire.MsgTx) {
+			a.TxIn[0].SignatureScript = sigA
+			b.TxIn[0].SignatureScript = sigA
+			b.TxIn[1].Witness = witness
+			a.TxIn[1].Witness = witness
+		}, true},
+		{"signed differently", func(t *testing.T, a, b *wire.MsgTx) {
+			a.TxIn[0].SignatureScript = sigA
+			b.TxIn[0].SignatureScript = sigB
+		}, false},
+		{"witness signed differently", func(t *testing.T, a, b *wire.MsgTx) {
+			a.TxIn[1].Witness = witness
+			b.TxIn[1].Witness = wire.TxWitness{[]byte{0xcc}}
+		}, false},
+		{"input differs", func(t *testing.T, a, b *wire.MsgTx) {
+			b.TxIn[1].PreviousOutPoint.Index++
+		}, false},
+		{"sequence differs", func(t *testing.T, a, b *wire.MsgTx) {
+			b.TxIn[1].Sequence--
+		}, false},
+		{"extra input", func(t *testing.T, a, b *wire.MsgTx) {
+			b.AddTxIn(wire.NewTxIn(outPoint(t, "3", 0), nil, nil))
+		}, false},
+		{"output differs", func(t *testing.T, a, b *wire.MsgTx) {
+			b.TxOut[1].Value++
+		}, false},
+		{"missing output", func(t *testing.T, a, b *wire.MsgTx) {
+			b.TxOut = b.TxOut[:2]
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFixture(t)
+			a, b := f.tx.Copy(), f.tx.Copy()
+			test.modify(t, a, b)
+			combined, err := Combine(a, b)
+			if !test.ok {
+				if !errors.Is(err, ErrHalvesMismatch) {
+					t.Fatalf("got %v, want %v", err, ErrHalvesMismatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Unsigned(combined).TxHash() != f.tx.TxHash() {
+				t.Fatal("combined transaction differs from the proposal")
+			}
+			for i, in := range combined.TxIn {
+				signed := len(in.SignatureScript) != 0 || len(in.Witness) != 0
+				if !signed {
+					t.Fatalf("input %d is not signed", i)
+				}
+			}
+		})
+	}
+}
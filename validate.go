@@ -0,0 +1,317 @@
+package nmcjson
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Consensus limits on name operations, which are the same on every network.
+const (
+	// MaxNameLength is the maximum length of a name in bytes.
+	MaxNameLength = 255
+
+	// MaxValueLength is the maximum length of a value in bytes.
+	MaxValueLength = 1023
+
+	// MaxRPCValueLength is the maximum length in bytes of a value given to
+	// the name RPCs of namecoind, which reject longer values although the
+	// consensus rules allow them up to MaxValueLength.
+	MaxRPCValueLength = 520
+
+	// MaxRandLength is the maximum length of a name_firstupdate rand in
+	// bytes.  The RPCs only take rands of RandLength or LegacyRandLength
+	// bytes, which are the ones namecoind generates.
+	MaxRandLength = 20
+)
+
+// validateName checks that name, encoded with enc, is not empty and fits the
+// consensus limit.
+func validateName(name string, enc Encoding) error {
+	if name == "" {
+		return errors.New("name is empty")
+	}
+	b, err := enc.Decode(name)
+	if err != nil {
+		return fmt.Errorf("invalid name: %v", err)
+	}
+	if len(b) > MaxNameLength {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d", ErrNameTooLong, len(b), MaxNameLength)
+	}
+	return nil
+}
+
+// validateValue checks that value, encoded with enc, fits the limit of the
+// name RPCs.
+func validateValue(value string, enc Encoding) error {
+	b, err := enc.Decode(value)
+	if err != nil {
+		return fmt.Errorf("invalid value: %v", err)
+	}
+	if len(b) > MaxRPCValueLength {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d", ErrValueTooLong, len(b), MaxRPCValueLength)
+	}
+	return nil
+}
+
+// validateTxid checks that txid is a hex encoded 32 byte transaction hash.
+func validateTxid(txid string) error {
+	b, err := hex.DecodeString(txid)
+	if err != nil || len(b) != 32 {
+		return fmt.Errorf("invalid txid %q: must be 64 hex characters", txid)
+	}
+	return nil
+}
+
+// validateRand checks that rand is a hex encoded rand of RandLength or
+// LegacyRandLength bytes.
+func validateRand(rand string) error {
+	b, err := hex.DecodeString(rand)
+	if err != nil || len(b) != RandLength && len(b) != LegacyRandLength {
+		return fmt.Errorf("invalid rand %q: must be %d or %d hex encoded bytes", rand, RandLength, LegacyRandLength)
+	}
+	return nil
+}
+
+// validate checks the destination and payment addresses of o against the
+// network of params, or any network if params is nil, and that a rand is
+// only given to name_new.
+func (o *NameTxOptions) validate(method string, params *Params) error {
+	if o == nil {
+		return nil
+	}
+	if o.Rand != "" {
+		if method != "name_new" {
+			return fmt.Errorf("%s: rand is only valid for name_new", method)
+		}
+		if err := validateRand(o.Rand); err != nil {
+			return err
+		}
+	}
+	if o.DestAddress != "" {
+		if err := validateAddress(o.DestAddress, params); err != nil {
+			return err
+		}
+	}
+	for addr, amount := range o.SendCoins {
+		if err := validateAddress(addr, params); err != nil {
+			return err
+		}
+		if amount <= 0 {
+			return fmt.Errorf("invalid amount %v for %s", amount, addr)
+		}
+	}
+	return nil
+}
+
+// encodings returns the name and value encodings of o, which may be nil.
+func (o *NameEncodingOptions) encodings() (Encoding, Encoding) {
+	if o == nil {
+		return "", ""
+	}
+	return o.NameEncoding, o.ValueEncoding
+}
+
+// txEncodings returns the name and value encodings of o, which may be nil.
+func (o *NameTxOptions) txEncodings() (Encoding, Encoding) {
+	if o == nil {
+		return "", ""
+	}
+	return o.NameEncodingOptions.encodings()
+}
+
+// Validate checks cmd against the limits of the name RPCs and its addresses against
+// the network of params, or any network if params is nil.
+func (cmd *NameNewCmd) Validate(params *Params) error {
+	nameEnc, _ := cmd.Options.txEncodings()
+	if err := validateName(cmd.Name, nameEnc); err != nil {
+		return err
+	}
+	return cmd.Options.validate("name_new", params)
+}
+
+// Validate checks cmd against the limits of the name RPCs and its addresses against
+// the network of params, or any network if params is nil.
+func (cmd *NameUpdateCmd) Validate(params *Params) error {
+	nameEnc, valueEnc := cmd.Options.txEncodings()
+	if err := validateName(cmd.Name, nameEnc); err != nil {
+		return err
+	}
+	if err := validateValue(cmd.Value, valueEnc); err != nil {
+		return err
+	}
+	return cmd.Options.validate("name_update", params)
+}
+
+// Validate checks cmd against the limits of the name RPCs and its addresses against
+// the network of params, or any network if params is nil.
+func (cmd *NameFirstUpdateCmd) Validate(params *Params) error {
+	nameEnc, valueEnc := cmd.Options.txEncodings()
+	if err := validateName(cmd.Name, nameEnc); err != nil {
+		return err
+	}
+	if err := validateRand(cmd.Rand); err != nil {
+		return err
+	}
+	if err := validateTxid(cmd.Txid); err != nil {
+		return err
+	}
+	if err := validateValue(cmd.Value, valueEnc); err != nil {
+		return err
+	}
+	return cmd.Options.validate("name_firstupdate", params)
+}
+
+// Validate checks that cmd names a valid name.
+func (cmd *NameShowCmd) Validate() error {
+	var nameEnc Encoding
+	if cmd.Options != nil {
+		nameEnc = cmd.Options.NameEncoding
+	}
+	return validateName(cmd.Name, nameEnc)
+}
+
+// Validate checks that the identifier of cmd, if set, is a valid name.
+func (cmd *NameListCmd) Validate() error {
+	if err := checkOptionals("name_list", []optional{
+		{"name", cmd.Identifier != nil},
+		{"options", cmd.Options != nil},
+	}); err != nil {
+		return err
+	}
+	if cmd.Identifier == nil || *cmd.Identifier == "" {
+		return nil
+	}
+	nameEnc, _ := cmd.Options.encodings()
+	return validateName(*cmd.Identifier, nameEnc)
+}
+
+// Validate checks that cmd names a valid name.
+func (cmd *NameHistoryCmd) Validate() error {
+	nameEnc, _ := cmd.Options.encodings()
+	return validateName(cmd.Name, nameEnc)
+}
+
+// Validate checks the start name and the limits of cmd.
+func (cmd *NameScanCmd) Validate() error {
+	if err := checkOptionals("name_scan", []optional{
+		{"start", cmd.StartName != nil},
+		{"count", cmd.MaxReturned != nil},
+		{"options", cmd.Options != nil},
+	}); err != nil {
+		return err
+	}
+	var opts NameScanOptions
+	if cmd.Options != nil {
+		opts = *cmd.Options
+	}
+	if cmd.StartName != nil && *cmd.StartName != "" {
+		if err := validateName(*cmd.StartName, opts.NameEncoding); err != nil {
+			return err
+		}
+	}
+	if cmd.MaxReturned != nil && *cmd.MaxReturned < 0 {
+		return fmt.Errorf("invalid count %d", *cmd.MaxReturned)
+	}
+	if opts.MinConf != nil && *opts.MinConf < 0 {
+		return fmt.Errorf("invalid minConf %d", *opts.MinConf)
+	}
+	if opts.MaxConf != nil && *opts.MaxConf < 0 {
+		return fmt.Errorf("invalid maxConf %d", *opts.MaxConf)
+	}
+	return nil
+}
+
+// Validate checks that the name of cmd, if set, is a valid name.
+func (cmd *NamePendingCmd) Validate() error {
+	if err := checkOptionals("name_pending", []optional{
+		{"name", cmd.Name != nil},
+		{"options", cmd.Options != nil},
+	}); err != nil {
+		return err
+	}
+	if cmd.Name == nil || *cmd.Name == "" {
+		return nil
+	}
+	nameEnc, _ := cmd.Options.encodings()
+	return validateName(*cmd.Name, nameEnc)
+}
+
+// Validate checks the limits and the stat parameter of cmd.
+func (cmd *NameFilterCmd) Validate() error {
+	if err := checkOptionals("name_filter", []optional{
+		{"regexp", cmd.Regexp != nil},
+		{"maxage", cmd.MaxAge != nil},
+		{"from", cmd.From != nil},
+		{"nb", cmd.Nb != nil},
+		{"stat", cmd.Stat != nil},
+	}); err != nil {
+		return err
+	}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"maxage", cmd.MaxAge}, {"from", cmd.From}, {"nb", cmd.Nb}} {
+		if p.value != nil && *p.value < 0 {
+			return fmt.Errorf("invalid %s %d", p.name, *p.value)
+		}
+	}
+	if cmd.Stat != nil && !cmd.IsStat() {
+		return fmt.Errorf("invalid stat %q: must be %q", *cmd.Stat, NameFilterStat)
+	}
+	return nil
+}
+
+// optional is an optional positional parameter of a command and whether it
+// is set.
+type optional struct {
+	name string
+	set  bool
+}
+
+// checkOptionals returns an error if an unset parameter of params is
+// followed by a set one.  The unset parameter would be sent as null, which
+// namecoind rejects instead of using its default.
+func checkOptionals(method string, params []optional) error {
+	for i := 1; i < len(params); i++ {
+		if params[i].set && !params[i-1].set {
+			return fmt.Errorf("%s: %s is set but %s is not", method, params[i].name, params[i-1].name)
+		}
+	}
+	return nil
+}
+
+// Validate checks the transaction, output and name operation of cmd against
+// the limits of the name RPCs.
+func (cmd *NameRawTransactionCmd) Validate() error {
+	if _, err := hex.DecodeString(cmd.HexTx); err != nil || cmd.HexTx == "" {
+		return errors.New("invalid raw transaction: not hex encoded")
+	}
+	if cmd.Vout < 0 {
+		return fmt.Errorf("invalid vout %d", cmd.Vout)
+	}
+	return cmd.NameOp.Validate()
+}
+
+// Validate checks the operation, name, value and rand of o against the
+// limits of the name RPCs.
+func (o NameOp) Validate() error {
+	if err := validateName(o.Name, ""); err != nil {
+		return err
+	}
+	switch o.Op {
+	case OpNameNew:
+		if o.Rand != "" {
+			return validateRand(o.Rand)
+		}
+		return nil
+	case OpNameFirstUpdate:
+		if err := validateRand(o.Rand); err != nil {
+			return err
+		}
+	case OpNameUpdate:
+	default:
+		return fmt.Errorf("invalid name operation %q", o.Op)
+	}
+	return validateValue(o.Value, "")
+}
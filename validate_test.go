@@ -0,0 +1,239 @@
+package nmcjson
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/kefkius/nmcjson/internal/addrcodec"
+)
+
+// Addresses of the 20 byte hash 000102...13 on each network.
+const (
+	mainP2PKH  = "MvaPQg5cFj92rWK4LW2zowoKpv8ZtuBncu"
+	mainP2SH   = "6EPs1STNZh4rxbsgP93Zu4BeHF5n9dtECo"
+	mainP2WPKH = "nc1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnjqsk4h"
+	testP2PKH  = "mfWyW5fc9NUj75YAnFgoRLrjxgLDn2MMth"
+	testP2SH   = "2MsFFCK16VhsCcvPXruztdzzcTZEQCbNKjJ"
+	testP2WPKH = "tn1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysn94gs7l"
+)
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		enc     Encoding
+		data    []byte
+		encoded string
+		err     bool
+	}{
+		{name: "default", data: []byte("d/\x00x"), encoded: "d/\x00x"},
+		{name: "ascii", enc: EncodingASCII, data: []byte("d/example"), encoded: "d/example"},
+		{name: "ascii control", enc: EncodingASCII, data: []byte("d/\nx"), err: true},
+		{name: "ascii non-ascii", enc: EncodingASCII, data: []byte("d/é"), err: true},
+		{name: "utf8", enc: EncodingUTF8, data: []byte("d/é"), encoded: "d/é"},
+		{name: "utf8 invalid", enc: EncodingUTF8, data: []byte{'d', '/', 0xff}, err: true},
+		{name: "hex", enc: EncodingHex, data: []byte{'d', '/', 0xff}, encoded: "642fff"},
+		{name: "unknown", enc: "base64", data: []byte("d/x"), err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.enc.Encode(test.data)
+			if (err != nil) != test.err {
+				t.Fatalf("Encode: got error %v, want error %v", err, test.err)
+			}
+			if test.err {
+				if _, err := test.enc.Decode(string(test.data)); err == nil && test.enc != EncodingHex {
+					t.Fatalf("Decode accepted %q", test.data)
+				}
+				return
+			}
+			if got != test.encoded {
+				t.Fatalf("Encode: got %q, want %q", got, test.encoded)
+			}
+			b, err := test.enc.Decode(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != string(test.data) {
+				t.Fatalf("Decode: got %q, want %q", b, test.data)
+			}
+		})
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		addr   string
+		params *Params
+		err    error
+	}{
+		{name: "mainnet P2PKH", addr: mainP2PKH, params: &MainNetParams},
+		{name: "mainnet P2SH", addr: mainP2SH, params: &MainNetParams},
+		{name: "mainnet P2WPKH", addr: mainP2WPKH, params: &MainNetParams},
+		{name: "testnet P2PKH", addr: testP2PKH, params: &TestNet3Params},
+		{name: "testnet P2SH", addr: testP2SH, params: &TestNet3Params},
+		{name: "testnet P2WPKH", addr: testP2WPKH, params: &TestNet3Params},
+		{name: "testnet P2PKH on regtest", addr: testP2PKH, params: &RegTestParams},
+		{name: "testnet on mainnet", addr: testP2PKH, params: &MainNetParams, err: addrcodec.ErrWrongNetwork},
+		{name: "mainnet on testnet", addr: mainP2WPKH, params: &TestNet3Params, err: addrcodec.ErrWrongNetwork},
+		{name: "testnet segwit on regtest", addr: testP2WPKH, params: &RegTestParams, err: addrcodec.ErrWrongNetwork},
+		{name: "any network", addr: testP2WPKH},
+		{name: "bad checksum", addr: mainP2PKH[:len(mainP2PKH)-1] + "v", params: &MainNetParams, err: addrcodec.ErrUnknownFormat},
+		{name: "bitcoin", addr: "1111111111111111111114oLvT2", err: addrcodec.ErrWrongNetwork},
+		{name: "garbage", addr: "not an address", err: addrcodec.ErrUnknownFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateAddress(test.addr, test.params)
+			if !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCmdValidate(t *testing.T) {
+	txid := strings.Repeat("ab", 32)
+	rand := strings.Repeat("00", RandLength)
+	longName := "d/" + strings.Repeat("x", MaxNameLength-1)
+	longValue := strings.Repeat("x", MaxValueLength+1)
+	tests := []struct {
+		name   string
+		cmd    interface{}
+		params *Params // network of the transaction commands
+		err    error
+		ok     bool
+	}{
+		{name: "name_new", cmd: &NameNewCmd{Name: "d/example"}, ok: true},
+		{name: "name_new empty", cmd: &NameNewCmd{}},
+		{name: "name_new longest", cmd: &NameNewCmd{Name: longName[1:]}, ok: true},
+		{name: "name_new too long", cmd: &NameNewCmd{Name: longName}, err: ErrNameTooLong},
+		{name: "name_new hex name", cmd: &NameNewCmd{Name: "642f78", Options: &NameTxOptions{
+			NameEncodingOptions: NameEncodingOptions{NameEncoding: EncodingHex}}}, ok: true},
+		{name: "name_new bad hex name", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{
+			NameEncodingOptions: NameEncodingOptions{NameEncoding: EncodingHex}}}},
+		{name: "name_new dest address", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{
+			DestAddress: testP2SH}}, ok: true},
+		{name: "name_new bad dest address", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{
+			DestAddress: "garbage"}}, err: addrcodec.ErrUnknownFormat},
+		{name: "name_new testnet dest address on testnet", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{
+			DestAddress: testP2PKH}}, params: &TestNet3Params, ok: true},
+		{name: "name_update testnet dest address on mainnet", cmd: &NameUpdateCmd{Name: "d/x", Options: &NameTxOptions{
+			DestAddress: testP2PKH}}, params: &MainNetParams, err: addrcodec.ErrWrongNetwork},
+		{name: "name_firstupdate testnet payment on mainnet", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand, Txid: txid,
+			Options: &NameTxOptions{SendCoins: map[string]float64{testP2PKH: 1}}}, params: &MainNetParams, err: addrcodec.ErrWrongNetwork},
+		{name: "name_new zero amount", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{
+			SendCoins: map[string]float64{mainP2PKH: 0}}}},
+		{name: "name_new rand", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{Rand: rand}}, ok: true},
+		{name: "name_new bad rand", cmd: &NameNewCmd{Name: "d/x", Options: &NameTxOptions{Rand: "xyz"}}},
+		{name: "name_update rand", cmd: &NameUpdateCmd{Name: "d/x", Options: &NameTxOptions{Rand: rand}}},
+		{name: "name_update", cmd: &NameUpdateCmd{Name: "d/x", Value: "{}"}, ok: true},
+		{name: "name_update empty value", cmd: &NameUpdateCmd{Name: "d/x"}, ok: true},
+		{name: "name_update too long", cmd: &NameUpdateCmd{Name: "d/x", Value: longValue}, err: ErrValueTooLong},
+		{name: "name_update longest for the RPCs", cmd: &NameUpdateCmd{Name: "d/x", Value: longValue[:MaxRPCValueLength]}, ok: true},
+		{name: "name_update too long for the RPCs", cmd: &NameUpdateCmd{Name: "d/x", Value: longValue[:MaxRPCValueLength+1]}, err: ErrValueTooLong},
+		{name: "name_update ascii value", cmd: &NameUpdateCmd{Name: "d/x", Value: "é", Options: &NameTxOptions{
+			NameEncodingOptions: NameEncodingOptions{ValueEncoding: EncodingASCII}}}},
+		{name: "name_firstupdate", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand, Txid: txid, Value: "{}"}, ok: true},
+		{name: "name_firstupdate legacy rand", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: "0011223344556677", Txid: txid}, ok: true},
+		{name: "name_firstupdate long rand", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand + "00", Txid: txid}},
+		{name: "name_firstupdate short rand", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand[2:], Txid: txid}},
+		{name: "name_firstupdate one byte rand", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: "00", Txid: txid}},
+		{name: "name_firstupdate no rand", cmd: &NameFirstUpdateCmd{Name: "d/x", Txid: txid}},
+		{name: "name_firstupdate short txid", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand, Txid: txid[2:]}},
+		{name: "name_firstupdate too long", cmd: &NameFirstUpdateCmd{Name: "d/x", Rand: rand, Txid: txid, Value: longValue}, err: ErrValueTooLong},
+		{name: "name_show", cmd: NewNameShowCmd("d/x", nil), ok: true},
+		{name: "name_show empty", cmd: NewNameShowCmd("", nil)},
+		{name: "name_list all", cmd: NewNameListCmd(nil, nil), ok: true},
+		{name: "name_scan negative count", cmd: NewNameScanCmd(nil, btcjson.Int(-1), nil)},
+		{name: "name_scan", cmd: NewNameScanCmd(btcjson.String(""), btcjson.Int(10), nil), ok: true},
+		{name: "name_filter stat", cmd: NewNameFilterCmd(nil, btcjson.Int(100), nil, nil, btcjson.String(NameFilterStat)), ok: true},
+		{name: "name_filter stat without maxage", cmd: NewNameFilterCmd(nil, nil, nil, nil, btcjson.String(NameFilterStat))},
+		{name: "name_filter bad stat", cmd: NewNameFilterCmd(nil, btcjson.Int(100), nil, nil, btcjson.String("count"))},
+		{name: "name_filter negative from", cmd: NewNameFilterCmd(nil, btcjson.Int(100), btcjson.Int(-1), nil, nil)},
+		{name: "name_scan options after nil count", cmd: &NameScanCmd{StartName: btcjson.String(""), Options: &NameScanOptions{}}},
+		{name: "name_filter maxage after nil regexp", cmd: &NameFilterCmd{MaxAge: btcjson.Int(100)}},
+		{name: "name_filter stat after nil nb", cmd: &NameFilterCmd{Regexp: btcjson.String(""), MaxAge: btcjson.Int(1),
+			From: btcjson.Int(0), Stat: btcjson.String(NameFilterStat)}},
+		{name: "nameop firstupdate", cmd: NameOp{Op: OpNameFirstUpdate, Name: "d/x", Rand: rand}, ok: true},
+		{name: "nameop firstupdate no rand", cmd: NameOp{Op: OpNameFirstUpdate, Name: "d/x"}},
+		{name: "nameop unknown", cmd: NameOp{Op: "name_delete", Name: "d/x"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			switch cmd := test.cmd.(type) {
+			case interface{ Validate(*Params) error }:
+				err = cmd.Validate(test.params)
+			case interface{ Validate() error }:
+				err = cmd.Validate()
+			}
+			if (err == nil) != test.ok {
+				t.Fatalf("got %v, want ok %v", err, test.ok)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestNewCmdDefaults(t *testing.T) {
+	Init()
+	tests := []struct {
+		name   string
+		cmd    interface{}
+		params string
+	}{
+		{name: "name_scan none", cmd: NewNameScanCmd(nil, nil, nil), params: `[]`},
+		{name: "name_scan count", cmd: NewNameScanCmd(nil, btcjson.Int(10), nil), params: `["",10]`},
+		{name: "name_scan options", cmd: NewNameScanCmd(nil, nil, &NameScanOptions{Prefix: "d/"}),
+			params: `["",500,{"prefix":"d/"}]`},
+		{name: "name_list options", cmd: NewNameListCmd(nil, &NameEncodingOptions{NameEncoding: EncodingHex}),
+			params: `["",{"nameEncoding":"hex"}]`},
+		{name: "name_pending options", cmd: NewNamePendingCmd(nil, &NameEncodingOptions{ValueEncoding: EncodingHex}),
+			params: `["",{"valueEncoding":"hex"}]`},
+		{name: "name_filter regexp", cmd: NewNameFilterCmd(btcjson.String("^d/"), nil, nil, nil, nil), params: `["^d/"]`},
+		{name: "name_filter maxage", cmd: NewNameFilterCmd(nil, btcjson.Int(100), nil, nil, nil), params: `["",100]`},
+		{name: "name_filter stat", cmd: NewNameFilterCmd(nil, btcjson.Int(100), nil, nil, btcjson.String(NameFilterStat)),
+			params: `["",100,0,0,"stat"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.cmd.(interface{ Validate() error }).Validate(); err != nil {
+				t.Fatal(err)
+			}
+			marshalled, err := btcjson.MarshalCmd(btcjson.RpcVersion1, 1, test.cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var request btcjson.Request
+			if err := json.Unmarshal(marshalled, &request); err != nil {
+				t.Fatal(err)
+			}
+			params, err := json.Marshal(request.Params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(params) != test.params {
+				t.Fatalf("got params %s, want %s", params, test.params)
+			}
+		})
+	}
+}
+
+func TestNewCmdValidates(t *testing.T) {
+	long := strings.Repeat("x", MaxNameLength+1)
+	if _, err := NewNameNewCmd(long, nil); !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf("NewNameNewCmd: got %v, want %v", err, ErrNameTooLong)
+	}
+	if _, err := NewNameUpdateCmd(long, "", nil); !errors.Is(err, ErrNameTooLong) {
+		t.Fatalf("NewNameUpdateCmd: got %v, want %v", err, ErrNameTooLong)
+	}
+	if _, err := NewNameFirstUpdateCmd("d/x", "zz", strings.Repeat("00", 32), "", nil); err == nil {
+		t.Fatal("NewNameFirstUpdateCmd accepted a rand which is not hex")
+	}
+}